@@ -0,0 +1,129 @@
+package sqlutil
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// maxBatchRows is the maximum number of rows in a single VALUES list
+const maxBatchRows = 1000
+
+func InsertAll(db Executor, models interface{}) (int64, error) {
+	return metadata.InsertAllContext(context.Background(), db, models)
+}
+
+// InsertAllContext inserts a slice of models using multi-row INSERT
+// statements. The slice is split into chunks that fit into the bind parameter
+// limit of the dialect and whose rows omit the same empty columns. The chunks
+// are not executed atomically unless db is a transaction. The generated
+// auto-increment keys are not written back into the models. The insert hooks
+// of every model are called before the first and after the last chunk.
+func InsertAllContext(ctx context.Context, db Executor, models interface{}) (int64, error) {
+	return metadata.InsertAllContext(ctx, db, models)
+}
+
+func (m *Metadata) InsertAll(db Executor, models interface{}) (int64, error) {
+	return m.InsertAllContext(context.Background(), db, models)
+}
+
+// InsertAllContext inserts a slice of models using the schemas and the
+// dialect of the registry
+func (m *Metadata) InsertAllContext(ctx context.Context, db Executor, models interface{}) (int64, error) {
+	entities, err := m.entitiesOf(models)
+	if err != nil || len(entities) == 0 {
+		return 0, err
+	}
+
+	for _, entity := range entities {
+		if err := entity.beforeInsert(ctx, db); err != nil {
+			return 0, err
+		}
+	}
+
+	inserted := entities
+	d := entities[0].Dialect()
+	now := entities[0].metadata.now()
+	table := entities[0].schema.Table
+	var total int64
+
+	for len(entities) > 0 {
+		columns := []string{}
+		rows := []string{}
+		values := make([]interface{}, 0)
+
+		size := len(entities)
+		if size > maxBatchRows {
+			size = maxBatchRows
+		}
+
+		for _, entity := range entities[:size] {
+			names, row, err := entity.insertValues(now)
+			if err != nil {
+				return total, err
+			}
+
+			// the rows without columns are inserted one by one with the
+			// default values
+			if len(rows) > 0 && (len(values)+len(row) > d.MaxParameters() || !equal(names, columns) || len(names) == 0) {
+				break
+			}
+
+			columns = names
+			rows = append(rows, placeholders(d, len(values)+1, len(row)))
+			values = append(values, row...)
+		}
+
+		statement := d.Insert(table, columns)
+		if len(columns) > 0 {
+			statement = fmt.Sprintf("INSERT INTO %s (%s) VALUES%s", d.Quote(table), strings.Join(quoteAll(d, columns), ","), strings.Join(rows, ","))
+		}
+		cnt, err := execSQL(ctx, db, d, statement, values...)
+		total += cnt
+
+		if err != nil {
+			return total, err
+		}
+
+		entities = entities[len(rows):]
+	}
+
+	for _, entity := range inserted {
+		if err := entity.afterInsert(ctx, db); err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+func (m *Metadata) entitiesOf(models interface{}) ([]*EntityContext, error) {
+	v := reflect.Indirect(reflect.ValueOf(models))
+
+	if !v.IsValid() || v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("Must be slice of structs or pointers to struct; got %T", models)
+	}
+
+	entities := []*EntityContext{}
+
+	for index := 0; index < v.Len(); index++ {
+		item := v.Index(index)
+		if item.Kind() == reflect.Ptr {
+			if item.IsNil() {
+				return nil, fmt.Errorf("Element at index %d is nil", index)
+			}
+		} else {
+			item = item.Addr()
+		}
+
+		entity, err := m.entity(item.Interface())
+		if err != nil {
+			return nil, err
+		}
+
+		entities = append(entities, entity)
+	}
+
+	return entities, nil
+}
@@ -0,0 +1,147 @@
+package sqlutil_test
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/phogolabs/sqlutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Batch", func() {
+	type student struct {
+		ID        string    `sql:"id,varchar(50),pk"`
+		Name      string    `sql:"name,text"`
+		CreatedAt time.Time `sql:"created_at,timestamp,not_null"`
+		UpdatedAt time.Time `sql:"updated_at,timestamp,not_null"`
+	}
+
+	BeforeEach(func() {
+		Expect(sqlutil.CreateTable(db, &student{})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		_, err := db.Exec("drop table student")
+		Expect(err).To(BeNil())
+	})
+
+	count := func() int64 {
+		var cnt int64
+		Expect(db.QueryRow("SELECT count(*) FROM student").Scan(&cnt)).To(Succeed())
+		return cnt
+	}
+
+	It("inserts a slice of pointers", func() {
+		students := []*student{
+			{ID: "1", Name: "Jack"},
+			{ID: "2", Name: "John"},
+		}
+
+		cnt, err := sqlutil.InsertAll(db, students)
+		Expect(err).To(BeNil())
+		Expect(cnt).To(Equal(int64(2)))
+		Expect(count()).To(Equal(int64(2)))
+
+		for _, s := range students {
+			Expect(s.CreatedAt).NotTo(Equal(time.Time{}))
+			Expect(s.UpdatedAt).To(BeTemporally("==", s.CreatedAt))
+		}
+
+		record := &student{ID: "2"}
+		Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+		Expect(record.Name).To(Equal("John"))
+	})
+
+	It("inserts a slice of values", func() {
+		students := []student{
+			{ID: "1", Name: "Jack"},
+			{ID: "2", Name: "John"},
+		}
+
+		cnt, err := sqlutil.InsertAll(db, students)
+		Expect(err).To(BeNil())
+		Expect(cnt).To(Equal(int64(2)))
+		Expect(students[1].CreatedAt).NotTo(Equal(time.Time{}))
+	})
+
+	It("splits the rows that exceed the parameter limit", func() {
+		students := []*student{}
+		for index := 0; index < 600; index++ {
+			students = append(students, &student{ID: fmt.Sprintf("%d", index)})
+		}
+
+		cnt, err := sqlutil.InsertAll(db, students)
+		Expect(err).To(BeNil())
+		Expect(cnt).To(Equal(int64(600)))
+		Expect(count()).To(Equal(int64(600)))
+	})
+
+	It("executes one statement per chunk", func() {
+		recordDB, err := sql.Open("sqlutil-recorder", "")
+		Expect(err).To(BeNil())
+		defer recordDB.Close()
+		recorder.Reset()
+
+		students := []*student{}
+		for index := 0; index < 600; index++ {
+			students = append(students, &student{ID: fmt.Sprintf("%d", index)})
+		}
+
+		cnt, err := sqlutil.InsertAll(recordDB, students)
+		Expect(err).To(BeNil())
+		Expect(cnt).To(Equal(int64(3)))
+		Expect(recorder.statements).To(HaveLen(3))
+	})
+
+	It("inserts the rows without columns with the default values", func() {
+		type visit struct {
+			ID int64 `sql:"id,integer,pk,auto"`
+		}
+
+		Expect(sqlutil.CreateTable(db, &visit{})).To(Succeed())
+		defer db.Exec("drop table visit")
+
+		cnt, err := sqlutil.InsertAll(db, []*visit{{}, {}})
+		Expect(err).To(BeNil())
+		Expect(cnt).To(Equal(int64(2)))
+
+		var total int64
+		Expect(db.QueryRow("SELECT count(*) FROM visit").Scan(&total)).To(Succeed())
+		Expect(total).To(Equal(int64(2)))
+	})
+
+	It("does nothing for an empty slice", func() {
+		cnt, err := sqlutil.InsertAll(db, []*student{})
+		Expect(err).To(BeNil())
+		Expect(cnt).To(BeZero())
+	})
+
+	Context("when the provided value is not a slice", func() {
+		It("returns an error", func() {
+			_, err := sqlutil.InsertAll(db, &student{})
+			Expect(err).To(MatchError(ContainSubstring("Must be slice")))
+		})
+	})
+
+	Context("when the provided value is nil", func() {
+		It("returns an error", func() {
+			var students *[]student
+
+			_, err := sqlutil.InsertAll(db, nil)
+			Expect(err).To(MatchError("Must be slice of structs or pointers to struct; got <nil>"))
+
+			_, err = sqlutil.InsertAll(db, students)
+			Expect(err).To(MatchError("Must be slice of structs or pointers to struct; got *[]sqlutil_test.student"))
+		})
+	})
+
+	Context("when the slice contains nil", func() {
+		It("returns an error", func() {
+			_, err := sqlutil.InsertAll(db, []*student{{ID: "1"}, nil})
+			Expect(err).To(MatchError("Element at index 1 is nil"))
+		})
+	})
+})
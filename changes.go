@@ -0,0 +1,62 @@
+package sqlutil
+
+import "reflect"
+
+// Change is the old and the new value of a column
+type Change struct {
+	Old interface{}
+	New interface{}
+}
+
+// Changes returns the changes of the columns since the values were last read
+// or written by Scan, QueryRow, Insert or Update of the entity context. It
+// returns nil when the values were not read or written yet.
+func (t *EntityContext) Changes() map[string]Change {
+	if t.snapshot == nil {
+		return nil
+	}
+
+	changes := map[string]Change{}
+
+	for _, column := range t.schema.Columns {
+		value := snapshotOf(t.lookup(column))
+		if old := t.snapshot[column.Name]; !reflect.DeepEqual(old, value) {
+			changes[column.Name] = Change{Old: old, New: value}
+		}
+	}
+
+	return changes
+}
+
+// takeSnapshot stores the current values of the columns
+func (t *EntityContext) takeSnapshot() {
+	t.snapshot = map[string]interface{}{}
+
+	for _, column := range t.schema.Columns {
+		t.snapshot[column.Name] = snapshotOf(t.lookup(column))
+	}
+}
+
+// snapshotOf returns a copy of the field value that does not share memory
+// with the model
+func snapshotOf(field reflect.Value) interface{} {
+	switch field.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Ptr:
+		if field.IsNil() {
+			return nil
+		}
+		return snapshotOf(field.Elem())
+	case reflect.Slice:
+		if field.IsNil() {
+			return nil
+		}
+
+		items := reflect.MakeSlice(field.Type(), field.Len(), field.Len())
+		reflect.Copy(items, field)
+		return items.Interface()
+	default:
+		return field.Interface()
+	}
+}
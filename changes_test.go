@@ -0,0 +1,114 @@
+package sqlutil_test
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/phogolabs/sqlutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Changes", func() {
+	type customer struct {
+		ID        string    `sql:"id,varchar(50),pk"`
+		Name      string    `sql:"name,text"`
+		Email     *string   `sql:"email,text"`
+		Avatar    []byte    `sql:"avatar,blob"`
+		UpdatedAt time.Time `sql:"updated_at,timestamp"`
+	}
+
+	var recordDB *sql.DB
+
+	BeforeEach(func() {
+		Expect(sqlutil.CreateTable(db, &customer{})).To(Succeed())
+		_, err := db.Exec("INSERT INTO customer (id,name,email,avatar,updated_at) VALUES ('1','Jack','jack@example.com',x'0102','2020-01-01 00:00:00')")
+		Expect(err).To(BeNil())
+
+		recordDB, err = sql.Open("sqlutil-recorder", "")
+		Expect(err).To(BeNil())
+		recorder.Reset()
+	})
+
+	AfterEach(func() {
+		Expect(recordDB.Close()).To(Succeed())
+		_, err := db.Exec("drop table customer")
+		Expect(err).To(BeNil())
+	})
+
+	It("returns nil before the values are read", func() {
+		entity := sqlutil.NewEntityContext(&customer{ID: "1"})
+		Expect(entity.Changes()).To(BeNil())
+	})
+
+	It("returns the changed columns", func() {
+		c := &customer{ID: "1"}
+		entity := sqlutil.NewEntityContext(c)
+		Expect(entity.QueryRow(db)).To(Succeed())
+		Expect(entity.Changes()).To(BeEmpty())
+
+		email := "john@example.com"
+		c.Name = "John"
+		c.Email = &email
+		c.Avatar[0] = 9
+
+		Expect(entity.Changes()).To(Equal(map[string]sqlutil.Change{
+			"name":   {Old: "Jack", New: "John"},
+			"email":  {Old: "jack@example.com", New: "john@example.com"},
+			"avatar": {Old: []byte{1, 2}, New: []byte{9, 2}},
+		}))
+	})
+
+	It("updates only the changed columns", func() {
+		c := &customer{ID: "1"}
+		entity := sqlutil.NewEntityContext(c)
+		Expect(entity.QueryRow(db)).To(Succeed())
+
+		c.Name = "John"
+		cnt, err := entity.Update(recordDB)
+		Expect(err).To(BeNil())
+		Expect(cnt).To(Equal(int64(1)))
+		Expect(recorder.statements).To(Equal([]string{
+			`UPDATE "customer" SET "name" = ?,"updated_at" = ? WHERE "id" = ?`,
+		}))
+		Expect(entity.Changes()).To(BeEmpty())
+	})
+
+	It("does not hit the database when nothing changed", func() {
+		c := &customer{ID: "1"}
+		entity := sqlutil.NewEntityContext(c)
+		Expect(entity.QueryRow(db)).To(Succeed())
+
+		cnt, err := entity.Update(recordDB)
+		Expect(err).To(BeNil())
+		Expect(cnt).To(BeZero())
+		Expect(recorder.statements).To(BeEmpty())
+		Expect(c.UpdatedAt).To(Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)))
+	})
+
+	It("tracks the changes after insert", func() {
+		c := &customer{ID: "2", Name: "Jane"}
+		entity := sqlutil.NewEntityContext(c)
+		_, err := entity.Insert(db)
+		Expect(err).To(BeNil())
+		Expect(entity.Changes()).To(BeEmpty())
+
+		c.Email = nil
+		c.Name = "Janet"
+		_, err = entity.Update(db)
+		Expect(err).To(BeNil())
+
+		record := &customer{ID: "2"}
+		Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+		Expect(record.Name).To(Equal("Janet"))
+	})
+
+	It("updates all columns without tracked values", func() {
+		_, err := sqlutil.Update(recordDB, &customer{ID: "1", Name: "John"})
+		Expect(err).To(BeNil())
+		Expect(recorder.statements).To(Equal([]string{
+			`UPDATE "customer" SET "name" = ?,"email" = ?,"avatar" = ?,"updated_at" = ? WHERE "id" = ?`,
+		}))
+	})
+})
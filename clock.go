@@ -0,0 +1,20 @@
+package sqlutil
+
+import "time"
+
+// Clock provides the current time of the audit and soft delete timestamps
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to the Clock interface
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// SetClock sets the clock of the default registry
+func SetClock(c Clock) {
+	metadata.SetClock(c)
+}
@@ -0,0 +1,140 @@
+package sqlutil
+
+import (
+	"context"
+	"database/sql"
+	"iter"
+	"reflect"
+)
+
+// Cursor iterates over the rows of a result set and scans every row into a
+// new model of type T. The column mapping is computed once per result set.
+type Cursor[T any] struct {
+	m       *Metadata
+	ctx     context.Context
+	db      Executor
+	rows    *sql.Rows
+	schema  *Schema
+	mapping []*Column
+}
+
+// NewCursor creates a cursor over rows. The cursor closes rows when the
+// iteration completes or stops early. The AfterScan hook of the models is
+// called without executor.
+func NewCursor[T any](rows *sql.Rows) (*Cursor[T], error) {
+	return NewMetadataCursor[T](metadata, rows)
+}
+
+// NewMetadataCursor creates a cursor over rows that uses the schemas of the
+// registry
+func NewMetadataCursor[T any](m *Metadata, rows *sql.Rows) (*Cursor[T], error) {
+	typ, err := typeOf(new(T))
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+
+	schema, err := m.Schema(typ)
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+
+	return &Cursor[T]{
+		m:       m,
+		ctx:     context.Background(),
+		rows:    rows,
+		schema:  schema,
+		mapping: scanMapping(schema, columns),
+	}, nil
+}
+
+func QueryCursor[T any](db Executor, query string, args ...interface{}) (*Cursor[T], error) {
+	return QueryCursorContext[T](context.Background(), db, query, args...)
+}
+
+func QueryCursorContext[T any](ctx context.Context, db Executor, query string, args ...interface{}) (*Cursor[T], error) {
+	return QueryMetadataCursorContext[T](ctx, metadata, db, query, args...)
+}
+
+// QueryMetadataCursorContext executes the query and returns a cursor over the
+// rows that uses the schemas of the registry
+func QueryMetadataCursorContext[T any](ctx context.Context, m *Metadata, db Executor, query string, args ...interface{}) (*Cursor[T], error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := NewMetadataCursor[T](m, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor.ctx = ctx
+	cursor.db = db
+	return cursor, nil
+}
+
+// Each calls fn for every row. The iteration stops at the first error
+// returned by fn, which is returned to the caller.
+func (c *Cursor[T]) Each(fn func(*T) error) error {
+	for item, err := range c.All() {
+		if err != nil {
+			return err
+		}
+
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// All returns an iterator over the rows. A scan or iteration error is yielded
+// once as the last element.
+func (c *Cursor[T]) All() iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		defer c.rows.Close()
+
+		for c.rows.Next() {
+			item, err := c.scan()
+			if !yield(item, err) || err != nil {
+				return
+			}
+		}
+
+		if err := c.rows.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+func (c *Cursor[T]) Close() error {
+	return c.rows.Close()
+}
+
+func (c *Cursor[T]) scan() (*T, error) {
+	item := new(T)
+	entity := &EntityContext{
+		metadata:   c.m,
+		schema:     c.schema,
+		modelValue: reflect.ValueOf(item).Elem(),
+	}
+
+	if err := c.rows.Scan(entity.scanValues(c.mapping)...); err != nil {
+		return nil, err
+	}
+
+	if err := entity.afterScan(c.ctx, c.db); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
@@ -0,0 +1,100 @@
+package sqlutil_test
+
+import (
+	"fmt"
+
+	"github.com/phogolabs/sqlutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cursor", func() {
+	type student struct {
+		ID   string `sql:"id,varchar(50),pk"`
+		Name string `sql:"name,text"`
+	}
+
+	BeforeEach(func() {
+		Expect(sqlutil.CreateTable(db, &student{})).To(Succeed())
+		_, err := db.Exec("INSERT INTO student (id,name) VALUES ('1','Jack'), ('2','John'), ('3','Peter')")
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		_, err := db.Exec("drop table student")
+		Expect(err).To(BeNil())
+	})
+
+	query := func() *sqlutil.Cursor[student] {
+		cursor, err := sqlutil.QueryCursor[student](db, "SELECT name, id, 'x' AS extra FROM student ORDER BY id")
+		Expect(err).To(BeNil())
+		return cursor
+	}
+
+	It("calls the callback for every row", func() {
+		names := []string{}
+
+		err := query().Each(func(s *student) error {
+			names = append(names, s.ID+":"+s.Name)
+			return nil
+		})
+
+		Expect(err).To(BeNil())
+		Expect(names).To(Equal([]string{"1:Jack", "2:John", "3:Peter"}))
+		Expect(db.Stats().InUse).To(BeZero())
+	})
+
+	It("stops when the callback returns an error", func() {
+		calls := 0
+
+		err := query().Each(func(s *student) error {
+			calls++
+			return fmt.Errorf("oh no")
+		})
+
+		Expect(err).To(MatchError("oh no"))
+		Expect(calls).To(Equal(1))
+		Expect(db.Stats().InUse).To(BeZero())
+	})
+
+	It("iterates the rows with range", func() {
+		ids := []string{}
+
+		for s, err := range query().All() {
+			Expect(err).To(BeNil())
+			ids = append(ids, s.ID)
+		}
+
+		Expect(ids).To(Equal([]string{"1", "2", "3"}))
+	})
+
+	It("closes the rows when the iteration stops early", func() {
+		for s, err := range query().All() {
+			Expect(err).To(BeNil())
+			Expect(s.ID).To(Equal("1"))
+			break
+		}
+
+		Expect(db.Stats().InUse).To(BeZero())
+	})
+
+	It("yields the scan errors", func() {
+		cursor, err := sqlutil.QueryCursor[student](db, "SELECT NULL AS id FROM student")
+		Expect(err).To(BeNil())
+
+		err = cursor.Each(func(s *student) error {
+			return nil
+		})
+
+		Expect(err).To(HaveOccurred())
+		Expect(db.Stats().InUse).To(BeZero())
+	})
+
+	Context("when the query fails", func() {
+		It("returns an error", func() {
+			_, err := sqlutil.QueryCursor[student](db, "SELECT * FROM unknown")
+			Expect(err).To(MatchError("no such table: unknown"))
+		})
+	})
+})
@@ -0,0 +1,598 @@
+package sqlutil
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Dialect describes the SQL flavour spoken by a database driver
+type Dialect interface {
+	// Name returns the name of the dialect
+	Name() string
+	// Placeholder returns the bind parameter for the given 1-based position
+	Placeholder(position int) string
+	// Quote quotes an identifier such as table, column or index name. Every
+	// part of a schema-qualified name is quoted separately.
+	Quote(identifier string) string
+	// DataType maps a data type declared in the sql tag to the dialect type
+	DataType(dataType string) string
+	// ColumnType returns the default data type of a column of the Go type or
+	// an empty string when the type is not supported
+	ColumnType(t reflect.Type) string
+	// AutoIncrement returns the data type of a key column generated by the
+	// database and reports whether it declares the primary key inline
+	AutoIncrement(dataType string) (string, bool)
+	// Insert returns the INSERT statement of a row with the given columns. A
+	// row without columns is inserted with the default values.
+	Insert(table string, columns []string) string
+	// InsertReturning returns an INSERT statement that returns the values of
+	// the returning columns or an empty string when it is not supported
+	InsertReturning(table string, columns, returning []string) string
+	// UpdateReturning returns an UPDATE statement with the given assignments
+	// and condition that returns the values of the returning columns or an
+	// empty string when it is not supported
+	UpdateReturning(table string, assignments []string, condition string, returning []string) string
+	// IfNotExists reports whether CREATE statements support IF NOT EXISTS
+	IfNotExists() bool
+	// CreateIndex returns the statement that creates the index of the table
+	CreateIndex(index *Index, table string) string
+	// MaxParameters returns the maximum number of bind parameters per statement
+	MaxParameters() int
+	// Upsert returns a statement that inserts the columns or updates the
+	// given update columns when a row with the same keys already exists
+	Upsert(table string, columns, keys, updates []string) string
+	// Limit returns the pagination clause for the given limit and offset,
+	// where zero means not set. Ordered reports whether the statement has an
+	// ORDER BY clause, which some databases require for pagination.
+	Limit(limit, offset int, ordered bool) string
+	// TranslateError translates a driver error to a sqlutil error such as
+	// *ConstraintError or returns it unchanged
+	TranslateError(err error) error
+	// Savepoint returns the statement that creates the savepoint
+	Savepoint(name string) string
+	// ReleaseSavepoint returns the statement that releases the savepoint or
+	// an empty string when the database does not release savepoints
+	ReleaseSavepoint(name string) string
+	// RollbackToSavepoint returns the statement that rolls back to the
+	// savepoint
+	RollbackToSavepoint(name string) string
+}
+
+var (
+	SQLiteDialect     Dialect = &sqliteDialect{}
+	PostgreSQLDialect Dialect = &postgresDialect{}
+	// MySQLDialect requires the connection to report the matched rows as
+	// affected, which is set by clientFoundRows=true in the DSN of the
+	// go-sql-driver/mysql driver. Otherwise an update that does not change
+	// the row fails with ErrNoRowsAffected.
+	MySQLDialect     Dialect = &mysqlDialect{}
+	SQLServerDialect Dialect = &sqlserverDialect{}
+)
+
+// SetDialect sets the dialect of the default registry
+func SetDialect(d Dialect) {
+	metadata.SetDialect(d)
+}
+
+type sqliteDialect struct{}
+
+var sqliteColumnTypes = map[logicalType]string{
+	boolType:     "boolean",
+	smallIntType: "integer",
+	intType:      "integer",
+	bigIntType:   "integer",
+	floatType:    "real",
+	doubleType:   "real",
+	decimalType:  "decimal",
+	stringType:   "text",
+	timeType:     "timestamp",
+	bytesType:    "blob",
+}
+
+func (d *sqliteDialect) Name() string {
+	return "sqlite3"
+}
+
+func (d *sqliteDialect) Placeholder(position int) string {
+	return "?"
+}
+
+func (d *sqliteDialect) Quote(identifier string) string {
+	return quoteIdentifier(identifier, `"`, `"`)
+}
+
+func (d *sqliteDialect) DataType(dataType string) string {
+	return dataType
+}
+
+func (d *sqliteDialect) ColumnType(t reflect.Type) string {
+	return sqliteColumnTypes[logicalTypeOf(t)]
+}
+
+func (d *sqliteDialect) AutoIncrement(dataType string) (string, bool) {
+	// AUTOINCREMENT is allowed only on an INTEGER PRIMARY KEY column
+	return "integer PRIMARY KEY AUTOINCREMENT", true
+}
+
+func (d *sqliteDialect) Insert(table string, columns []string) string {
+	return insertStatement(d, table, columns)
+}
+
+func (d *sqliteDialect) InsertReturning(table string, columns, returning []string) string {
+	return ""
+}
+
+func (d *sqliteDialect) UpdateReturning(table string, assignments []string, condition string, returning []string) string {
+	return ""
+}
+
+func (d *sqliteDialect) IfNotExists() bool {
+	return true
+}
+
+func (d *sqliteDialect) CreateIndex(index *Index, table string) string {
+	// SQLite qualifies the index name instead of the table name
+	if position := strings.LastIndex(table, "."); position >= 0 {
+		qualified := *index
+		qualified.Name = table[:position+1] + index.Name
+		return createIndex(d, &qualified, table[position+1:])
+	}
+
+	return createIndex(d, index, table)
+}
+
+func (d *sqliteDialect) MaxParameters() int {
+	return 999
+}
+
+func (d *sqliteDialect) Upsert(table string, columns, keys, updates []string) string {
+	return onConflictUpsert(d, table, columns, keys, updates)
+}
+
+func (d *sqliteDialect) Limit(limit, offset int, ordered bool) string {
+	return limitOffset(limit, offset, "-1")
+}
+
+func (d *sqliteDialect) TranslateError(err error) error {
+	return translateSQLiteError(err)
+}
+
+func (d *sqliteDialect) Savepoint(name string) string {
+	return "SAVEPOINT " + name
+}
+
+func (d *sqliteDialect) ReleaseSavepoint(name string) string {
+	return "RELEASE SAVEPOINT " + name
+}
+
+func (d *sqliteDialect) RollbackToSavepoint(name string) string {
+	return "ROLLBACK TO SAVEPOINT " + name
+}
+
+type postgresDialect struct{}
+
+var postgresColumnTypes = map[logicalType]string{
+	boolType:     "boolean",
+	smallIntType: "smallint",
+	intType:      "integer",
+	bigIntType:   "bigint",
+	floatType:    "real",
+	doubleType:   "double precision",
+	decimalType:  "numeric",
+	stringType:   "text",
+	timeType:     "timestamp with time zone",
+	bytesType:    "bytea",
+}
+
+var postgresDataTypes = map[string]string{
+	"datetime": "timestamp",
+	"blob":     "bytea",
+	"double":   "double precision",
+	"tinyint":  "smallint",
+}
+
+var postgresSerialTypes = map[string]string{
+	"smallint": "smallserial",
+	"integer":  "serial",
+	"int":      "serial",
+	"bigint":   "bigserial",
+}
+
+func (d *postgresDialect) Name() string {
+	return "postgres"
+}
+
+func (d *postgresDialect) Placeholder(position int) string {
+	return fmt.Sprintf("$%d", position)
+}
+
+func (d *postgresDialect) Quote(identifier string) string {
+	return quoteIdentifier(identifier, `"`, `"`)
+}
+
+func (d *postgresDialect) DataType(dataType string) string {
+	return mapDataType(postgresDataTypes, dataType)
+}
+
+func (d *postgresDialect) ColumnType(t reflect.Type) string {
+	return postgresColumnTypes[logicalTypeOf(t)]
+}
+
+func (d *postgresDialect) AutoIncrement(dataType string) (string, bool) {
+	if serial, ok := postgresSerialTypes[strings.ToLower(dataType)]; ok {
+		return serial, false
+	}
+	return dataType + " GENERATED BY DEFAULT AS IDENTITY", false
+}
+
+func (d *postgresDialect) Insert(table string, columns []string) string {
+	return insertStatement(d, table, columns)
+}
+
+func (d *postgresDialect) InsertReturning(table string, columns, returning []string) string {
+	return fmt.Sprintf("%s RETURNING %s", insertStatement(d, table, columns), strings.Join(quoteAll(d, returning), ","))
+}
+
+func (d *postgresDialect) UpdateReturning(table string, assignments []string, condition string, returning []string) string {
+	return fmt.Sprintf("UPDATE %s SET %s WHERE %s RETURNING %s",
+		d.Quote(table),
+		strings.Join(assignments, ","),
+		condition,
+		strings.Join(quoteAll(d, returning), ","))
+}
+
+func (d *postgresDialect) IfNotExists() bool {
+	return true
+}
+
+func (d *postgresDialect) CreateIndex(index *Index, table string) string {
+	return createIndex(d, index, table)
+}
+
+func (d *postgresDialect) MaxParameters() int {
+	return 65535
+}
+
+func (d *postgresDialect) Upsert(table string, columns, keys, updates []string) string {
+	return onConflictUpsert(d, table, columns, keys, updates)
+}
+
+func (d *postgresDialect) Limit(limit, offset int, ordered bool) string {
+	return limitOffset(limit, offset, "")
+}
+
+func (d *postgresDialect) TranslateError(err error) error {
+	return translatePostgresError(err)
+}
+
+func (d *postgresDialect) Savepoint(name string) string {
+	return "SAVEPOINT " + name
+}
+
+func (d *postgresDialect) ReleaseSavepoint(name string) string {
+	return "RELEASE SAVEPOINT " + name
+}
+
+func (d *postgresDialect) RollbackToSavepoint(name string) string {
+	return "ROLLBACK TO SAVEPOINT " + name
+}
+
+type mysqlDialect struct{}
+
+var mysqlColumnTypes = map[logicalType]string{
+	boolType:     "tinyint(1)",
+	smallIntType: "smallint",
+	intType:      "int",
+	bigIntType:   "bigint",
+	floatType:    "float",
+	doubleType:   "double",
+	decimalType:  "decimal(38,10)",
+	stringType:   "varchar(255)",
+	timeType:     "datetime(6)",
+	bytesType:    "longblob",
+}
+
+var mysqlDataTypes = map[string]string{
+	"bytea":            "blob",
+	"double precision": "double",
+	"boolean":          "tinyint(1)",
+}
+
+func (d *mysqlDialect) Name() string {
+	return "mysql"
+}
+
+func (d *mysqlDialect) Placeholder(position int) string {
+	return "?"
+}
+
+func (d *mysqlDialect) Quote(identifier string) string {
+	return quoteIdentifier(identifier, "`", "`")
+}
+
+func (d *mysqlDialect) DataType(dataType string) string {
+	return mapDataType(mysqlDataTypes, dataType)
+}
+
+func (d *mysqlDialect) ColumnType(t reflect.Type) string {
+	return mysqlColumnTypes[logicalTypeOf(t)]
+}
+
+func (d *mysqlDialect) AutoIncrement(dataType string) (string, bool) {
+	return dataType + " AUTO_INCREMENT", false
+}
+
+func (d *mysqlDialect) Insert(table string, columns []string) string {
+	// MySQL does not support DEFAULT VALUES but accepts an empty row
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES%s", d.Quote(table), strings.Join(quoteAll(d, columns), ","), placeholders(d, 1, len(columns)))
+}
+
+func (d *mysqlDialect) InsertReturning(table string, columns, returning []string) string {
+	return ""
+}
+
+func (d *mysqlDialect) UpdateReturning(table string, assignments []string, condition string, returning []string) string {
+	return ""
+}
+
+func (d *mysqlDialect) IfNotExists() bool {
+	return true
+}
+
+func (d *mysqlDialect) CreateIndex(index *Index, table string) string {
+	return createIndex(d, index, table)
+}
+
+func (d *mysqlDialect) MaxParameters() int {
+	return 65535
+}
+
+func (d *mysqlDialect) Upsert(table string, columns, keys, updates []string) string {
+	assignments := []string{}
+	for _, column := range updates {
+		assignments = append(assignments, fmt.Sprintf("%s = VALUES(%s)", d.Quote(column), d.Quote(column)))
+	}
+
+	if len(assignments) == 0 {
+		assignments = append(assignments, fmt.Sprintf("%s = %s", d.Quote(keys[0]), d.Quote(keys[0])))
+	}
+
+	return fmt.Sprintf("%s ON DUPLICATE KEY UPDATE %s", d.Insert(table, columns), strings.Join(assignments, ","))
+}
+
+func (d *mysqlDialect) Limit(limit, offset int, ordered bool) string {
+	return limitOffset(limit, offset, "18446744073709551615")
+}
+
+func (d *mysqlDialect) TranslateError(err error) error {
+	return translateMySQLError(err)
+}
+
+func (d *mysqlDialect) Savepoint(name string) string {
+	return "SAVEPOINT " + name
+}
+
+func (d *mysqlDialect) ReleaseSavepoint(name string) string {
+	return "RELEASE SAVEPOINT " + name
+}
+
+func (d *mysqlDialect) RollbackToSavepoint(name string) string {
+	return "ROLLBACK TO SAVEPOINT " + name
+}
+
+type sqlserverDialect struct{}
+
+var sqlserverColumnTypes = map[logicalType]string{
+	boolType:     "bit",
+	smallIntType: "smallint",
+	intType:      "int",
+	bigIntType:   "bigint",
+	floatType:    "real",
+	doubleType:   "float",
+	decimalType:  "decimal(38,10)",
+	stringType:   "nvarchar(255)",
+	timeType:     "datetime2",
+	bytesType:    "varbinary(max)",
+}
+
+var sqlserverDataTypes = map[string]string{
+	"timestamp": "datetime2",
+	"datetime":  "datetime2",
+	"text":      "nvarchar(max)",
+	"boolean":   "bit",
+	"blob":      "varbinary(max)",
+	"bytea":     "varbinary(max)",
+	"double":    "float",
+}
+
+func (d *sqlserverDialect) Name() string {
+	return "sqlserver"
+}
+
+func (d *sqlserverDialect) Placeholder(position int) string {
+	return fmt.Sprintf("@p%d", position)
+}
+
+func (d *sqlserverDialect) Quote(identifier string) string {
+	return quoteIdentifier(identifier, "[", "]")
+}
+
+func (d *sqlserverDialect) DataType(dataType string) string {
+	return mapDataType(sqlserverDataTypes, dataType)
+}
+
+func (d *sqlserverDialect) ColumnType(t reflect.Type) string {
+	return sqlserverColumnTypes[logicalTypeOf(t)]
+}
+
+func (d *sqlserverDialect) AutoIncrement(dataType string) (string, bool) {
+	return dataType + " IDENTITY(1,1)", false
+}
+
+func (d *sqlserverDialect) Insert(table string, columns []string) string {
+	return insertStatement(d, table, columns)
+}
+
+func (d *sqlserverDialect) InsertReturning(table string, columns, returning []string) string {
+	if len(columns) == 0 {
+		return fmt.Sprintf("INSERT INTO %s OUTPUT %s DEFAULT VALUES", d.Quote(table), d.inserted(returning))
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s) OUTPUT %s VALUES%s",
+		d.Quote(table),
+		strings.Join(quoteAll(d, columns), ","),
+		d.inserted(returning),
+		placeholders(d, 1, len(columns)))
+}
+
+func (d *sqlserverDialect) UpdateReturning(table string, assignments []string, condition string, returning []string) string {
+	return fmt.Sprintf("UPDATE %s SET %s OUTPUT %s WHERE %s",
+		d.Quote(table),
+		strings.Join(assignments, ","),
+		d.inserted(returning),
+		condition)
+}
+
+// inserted returns the OUTPUT list of the columns of the inserted or updated
+// row
+func (d *sqlserverDialect) inserted(columns []string) string {
+	output := []string{}
+	for _, column := range columns {
+		output = append(output, "INSERTED."+d.Quote(column))
+	}
+	return strings.Join(output, ",")
+}
+
+func (d *sqlserverDialect) IfNotExists() bool {
+	return false
+}
+
+func (d *sqlserverDialect) CreateIndex(index *Index, table string) string {
+	return createIndex(d, index, table)
+}
+
+func (d *sqlserverDialect) MaxParameters() int {
+	return 2100
+}
+
+func (d *sqlserverDialect) Upsert(table string, columns, keys, updates []string) string {
+	conditions := []string{}
+	for _, column := range keys {
+		conditions = append(conditions, fmt.Sprintf("target.%s = source.%s", d.Quote(column), d.Quote(column)))
+	}
+
+	assignments := []string{}
+	for _, column := range updates {
+		assignments = append(assignments, fmt.Sprintf("target.%s = source.%s", d.Quote(column), d.Quote(column)))
+	}
+
+	matched := ""
+	if len(assignments) > 0 {
+		matched = fmt.Sprintf(" WHEN MATCHED THEN UPDATE SET %s", strings.Join(assignments, ","))
+	}
+
+	quoted := strings.Join(quoteAll(d, columns), ",")
+	return fmt.Sprintf("MERGE INTO %s WITH (HOLDLOCK) AS target USING (VALUES%s) AS source (%s) ON %s%s WHEN NOT MATCHED THEN INSERT (%s) VALUES(source.%s);",
+		d.Quote(table),
+		placeholders(d, 1, len(columns)),
+		quoted,
+		strings.Join(conditions, " AND "),
+		matched,
+		quoted,
+		strings.Join(quoteAll(d, columns), ",source."))
+}
+
+func (d *sqlserverDialect) Limit(limit, offset int, ordered bool) string {
+	if limit == 0 && offset == 0 {
+		return ""
+	}
+
+	clause := fmt.Sprintf("OFFSET %d ROWS", offset)
+	if !ordered {
+		// OFFSET and FETCH are allowed only after ORDER BY
+		clause = "ORDER BY (SELECT NULL) " + clause
+	}
+
+	if limit > 0 {
+		clause += fmt.Sprintf(" FETCH NEXT %d ROWS ONLY", limit)
+	}
+
+	return clause
+}
+
+func (d *sqlserverDialect) TranslateError(err error) error {
+	return translateSQLServerError(err)
+}
+
+func (d *sqlserverDialect) Savepoint(name string) string {
+	return "SAVE TRANSACTION " + name
+}
+
+func (d *sqlserverDialect) ReleaseSavepoint(name string) string {
+	// the savepoints are released with the transaction
+	return ""
+}
+
+func (d *sqlserverDialect) RollbackToSavepoint(name string) string {
+	return "ROLLBACK TRANSACTION " + name
+}
+
+func insertStatement(d Dialect, table string, columns []string) string {
+	if len(columns) == 0 {
+		return fmt.Sprintf("INSERT INTO %s DEFAULT VALUES", d.Quote(table))
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES%s", d.Quote(table), strings.Join(quoteAll(d, columns), ","), placeholders(d, 1, len(columns)))
+}
+
+func onConflictUpsert(d Dialect, table string, columns, keys, updates []string) string {
+	assignments := []string{}
+	for _, column := range updates {
+		assignments = append(assignments, fmt.Sprintf("%s = excluded.%s", d.Quote(column), d.Quote(column)))
+	}
+
+	action := "NOTHING"
+	if len(assignments) > 0 {
+		action = fmt.Sprintf("UPDATE SET %s", strings.Join(assignments, ","))
+	}
+
+	return fmt.Sprintf("%s ON CONFLICT (%s) DO %s", insertStatement(d, table, columns), strings.Join(quoteAll(d, keys), ","), action)
+}
+
+// limitOffset renders LIMIT and OFFSET clauses. The unlimited value is used
+// as LIMIT when only the offset is set for databases that require it.
+func limitOffset(limit, offset int, unlimited string) string {
+	clauses := []string{}
+
+	if limit > 0 {
+		clauses = append(clauses, fmt.Sprintf("LIMIT %d", limit))
+	} else if offset > 0 && unlimited != "" {
+		clauses = append(clauses, "LIMIT "+unlimited)
+	}
+
+	if offset > 0 {
+		clauses = append(clauses, fmt.Sprintf("OFFSET %d", offset))
+	}
+
+	return strings.Join(clauses, " ")
+}
+
+// quoteIdentifier quotes every part of a qualified identifier such as
+// schema.table
+func quoteIdentifier(identifier, left, right string) string {
+	parts := strings.Split(identifier, ".")
+
+	for index, part := range parts {
+		parts[index] = left + strings.Replace(part, right, right+right, -1) + right
+	}
+
+	return strings.Join(parts, ".")
+}
+
+func mapDataType(types map[string]string, dataType string) string {
+	if mapped, ok := types[strings.ToLower(dataType)]; ok {
+		return mapped
+	}
+	return dataType
+}
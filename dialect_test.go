@@ -0,0 +1,158 @@
+package sqlutil_test
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/phogolabs/sqlutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Dialect", func() {
+	type account struct {
+		ID        string       `sql:"id,varchar(50),pk"`
+		Name      string       `sql:"name,text,not_null" sqlindex:"account_name"`
+		Email     string       `sql:"email,varchar(255)" sqlindex:"account_email,unique"`
+		Active    bool         `sql:"active,boolean"`
+		CreatedAt time.Time    `sql:"created_at,timestamp"`
+		Balance   float64      `sql:"balance,,not_null"`
+		Avatar    []byte       `sql:"avatar"`
+		LastLogin sql.NullTime `sql:"last_login"`
+	}
+
+	var recordDB *sql.DB
+
+	BeforeEach(func() {
+		var err error
+		recordDB, err = sql.Open("sqlutil-recorder", "")
+		Expect(err).To(BeNil())
+		recorder.Reset()
+	})
+
+	AfterEach(func() {
+		Expect(recordDB.Close()).To(Succeed())
+	})
+
+	dialects := []sqlutil.Dialect{
+		sqlutil.SQLiteDialect,
+		sqlutil.PostgreSQLDialect,
+		sqlutil.MySQLDialect,
+		sqlutil.SQLServerDialect,
+	}
+
+	for _, dialect := range dialects {
+		d := dialect
+
+		It(fmt.Sprintf("generates %s statements that match the golden file", d.Name()), func() {
+			entity := func() *sqlutil.EntityContext {
+				return sqlutil.NewEntityContext(&account{ID: "1", Name: "Jack"}).WithDialect(d)
+			}
+
+			Expect(entity().CreateTable(recordDB)).To(Succeed())
+			_, err := entity().Insert(recordDB)
+			Expect(err).To(BeNil())
+			_, err = entity().Update(recordDB)
+			Expect(err).To(BeNil())
+			_, err = entity().Update(recordDB, sqlutil.Fields{"name": "John"})
+			Expect(err).To(BeNil())
+			_, err = entity().Upsert(recordDB)
+			Expect(err).To(BeNil())
+			_, err = entity().OnConflict("account_email").Upsert(recordDB, sqlutil.Fields{"name": "John"})
+			Expect(err).To(BeNil())
+			_, err = entity().Delete(recordDB)
+			Expect(err).To(BeNil())
+			Expect(entity().QueryRow(recordDB)).To(MatchError(sql.ErrNoRows))
+
+			Expect(strings.Join(recorder.statements, "\n;\n") + "\n").To(MatchGolden(filepath.Join("dialect", d.Name())))
+		})
+	}
+
+	for _, dialect := range dialects {
+		d := dialect
+
+		It(fmt.Sprintf("generates %s statements for auto-increment keys and generated columns", d.Name()), func() {
+			type ticket struct {
+				ID     int64  `sql:"id,bigint,pk,auto"`
+				Title  string `sql:"title,text"`
+				Slug   string `sql:"slug,text,generated"`
+				Status string `sql:"status,text"`
+			}
+
+			entity := sqlutil.NewEntityContext(&ticket{Title: "bug"}).WithDialect(d)
+			Expect(entity.CreateTable(recordDB)).To(Succeed())
+			// the recorder returns no rows for the statements with RETURNING
+			// and the selects of the generated columns
+			_, _ = entity.Insert(recordDB)
+			_, _ = entity.Returning("status").Update(recordDB)
+
+			Expect(strings.Join(recorder.statements, "\n;\n") + "\n").To(MatchGolden(filepath.Join("dialect", d.Name()+"_generated")))
+		})
+	}
+
+	It("inserts the rows without columns with the default values", func() {
+		Expect(sqlutil.SQLiteDialect.Insert("visit", nil)).To(Equal(`INSERT INTO "visit" DEFAULT VALUES`))
+		Expect(sqlutil.PostgreSQLDialect.InsertReturning("visit", nil, []string{"id"})).To(Equal(`INSERT INTO "visit" DEFAULT VALUES RETURNING "id"`))
+		Expect(sqlutil.MySQLDialect.Insert("visit", nil)).To(Equal("INSERT INTO `visit` () VALUES()"))
+		Expect(sqlutil.SQLServerDialect.Insert("visit", nil)).To(Equal("INSERT INTO [visit] DEFAULT VALUES"))
+		Expect(sqlutil.SQLServerDialect.InsertReturning("visit", nil, []string{"id"})).To(Equal("INSERT INTO [visit] OUTPUT INSERTED.[id] DEFAULT VALUES"))
+	})
+
+	It("quotes identifiers that contain the quote character", func() {
+		Expect(sqlutil.SQLiteDialect.Quote(`a"b`)).To(Equal(`"a""b"`))
+		Expect(sqlutil.MySQLDialect.Quote("a`b")).To(Equal("`a``b`"))
+		Expect(sqlutil.SQLServerDialect.Quote("a]b")).To(Equal("[a]]b]"))
+	})
+
+	It("infers the column types from the Go types", func() {
+		type decimal struct {
+			value string
+		}
+
+		types := map[interface{}]string{
+			true:                 "boolean",
+			int8(1):              "smallint",
+			int32(1):             "integer",
+			int64(1):             "bigint",
+			1:                    "bigint",
+			float32(1):           "real",
+			float64(1):           "double precision",
+			"text":               "text",
+			time.Time{}:          "timestamp with time zone",
+			decimal{}:            "numeric",
+			sql.NullString{}:     "text",
+			sql.NullInt64{}:      "bigint",
+			sql.Null[bool]{}:     "boolean",
+			struct{ A, B int }{}: "",
+		}
+
+		for value, dataType := range types {
+			Expect(sqlutil.PostgreSQLDialect.ColumnType(reflect.TypeOf(value))).To(Equal(dataType), fmt.Sprintf("%T", value))
+		}
+
+		Expect(sqlutil.PostgreSQLDialect.ColumnType(reflect.TypeOf([]byte{}))).To(Equal("bytea"))
+		Expect(sqlutil.PostgreSQLDialect.ColumnType(reflect.TypeOf(new(int64)))).To(Equal("bigint"))
+		Expect(sqlutil.SQLiteDialect.ColumnType(reflect.TypeOf(""))).To(Equal("text"))
+		Expect(sqlutil.MySQLDialect.ColumnType(reflect.TypeOf(""))).To(Equal("varchar(255)"))
+		Expect(sqlutil.SQLServerDialect.ColumnType(reflect.TypeOf(true))).To(Equal("bit"))
+	})
+
+	It("quotes every part of a qualified identifier", func() {
+		Expect(sqlutil.PostgreSQLDialect.Quote("billing.invoices")).To(Equal(`"billing"."invoices"`))
+		Expect(sqlutil.MySQLDialect.Quote("billing.invoices")).To(Equal("`billing`.`invoices`"))
+		Expect(sqlutil.SQLServerDialect.Quote("dbo.invoices")).To(Equal("[dbo].[invoices]"))
+	})
+
+	It("maps the declared data types", func() {
+		Expect(sqlutil.SQLiteDialect.DataType("boolean")).To(Equal("boolean"))
+		Expect(sqlutil.PostgreSQLDialect.DataType("BLOB")).To(Equal("bytea"))
+		Expect(sqlutil.MySQLDialect.DataType("boolean")).To(Equal("tinyint(1)"))
+		Expect(sqlutil.SQLServerDialect.DataType("timestamp")).To(Equal("datetime2"))
+		Expect(sqlutil.SQLServerDialect.DataType("varchar(50)")).To(Equal("varchar(50)"))
+	})
+})
@@ -1,6 +1,7 @@
 package sqlutil
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"reflect"
@@ -11,158 +12,839 @@ import (
 const (
 	FieldCreatedAt = "created_at"
 	FieldUpdatedAt = "updated_at"
+	FieldDeletedAt = "deleted_at"
 )
 
+// Fields are the values set by Update and Upsert by column name or Go field
+// name. The values are converted to the types of the fields.
 type Fields map[string]interface{}
 
 type EntityContext struct {
-	schema     *Schema
-	modelValue reflect.Value
+	metadata      *Metadata
+	schema        *Schema
+	modelValue    reflect.Value
+	dialect       Dialect
+	conflictIndex string
+	returning     []string
+	withDeleted   bool
+	snapshot      map[string]interface{}
 }
 
 func NewEntityContext(model interface{}) *EntityContext {
-	typ, err := typeOf(model)
-	if err != nil {
-		panic(err)
-	}
+	return metadata.NewEntityContext(model)
+}
 
-	schema, err := metadata.Schema(typ)
-	if err != nil {
-		panic(err)
-	}
+func (t *EntityContext) WithDialect(d Dialect) *EntityContext {
+	t.dialect = d
+	return t
+}
 
-	return &EntityContext{
-		modelValue: valueOf(model),
-		schema:     schema,
+// OnConflict sets the unique index used as conflict target by Upsert
+// instead of the primary key
+func (t *EntityContext) OnConflict(index string) *EntityContext {
+	t.conflictIndex = index
+	return t
+}
+
+// Returning sets the columns that are read back into the model after Insert
+// and Update in addition to the generated columns
+func (t *EntityContext) Returning(columns ...string) *EntityContext {
+	t.returning = append(t.returning, columns...)
+	return t
+}
+
+// IncludeDeleted makes QueryRow find the soft-deleted row as well
+func (t *EntityContext) IncludeDeleted() *EntityContext {
+	t.withDeleted = true
+	return t
+}
+
+func (t *EntityContext) Dialect() Dialect {
+	if t.dialect == nil {
+		return t.metadata.Dialect()
 	}
+	return t.dialect
 }
 
+// Scan scans the row into the model and calls its AfterScan hook without
+// executor
 func (t *EntityContext) Scan(scanner Scanner) error {
+	if err := t.scan(scanner); err != nil {
+		return err
+	}
+
+	t.takeSnapshot()
+	return t.afterScan(context.Background(), nil)
+}
+
+func (t *EntityContext) scan(scanner Scanner) error {
 	columns, err := scanner.Columns()
 	if err != nil {
 		return err
 	}
 
-	add := len(columns) == 0
+	return scanner.Scan(t.scanValues(scanMapping(t.schema, columns))...)
+}
 
-	mapping := make(map[string]int)
-	for _, c := range t.schema.Columns {
-		mapping[c.Name] = c.Index
-		if add {
-			columns = append(columns, c.Name)
-		}
+// scanMapping returns the schema column of every result column or nil when
+// the result column is not mapped. When no result columns are provided the
+// values are expected in the order of the schema columns.
+func scanMapping(schema *Schema, columns []string) []*Column {
+	if len(columns) == 0 {
+		return schema.Columns
 	}
 
-	values := make([]interface{}, 0)
-	var value interface{}
+	byName := make(map[string]*Column)
+	for _, c := range schema.Columns {
+		byName[c.Name] = c
+	}
 
-	for _, column := range columns {
-		if idx, ok := mapping[column]; ok {
-			value = t.modelValue.Field(idx).Addr().Interface()
+	mapping := make([]*Column, len(columns))
+	for index, column := range columns {
+		mapping[index] = byName[column]
+	}
+
+	return mapping
+}
+
+func (t *EntityContext) scanValues(mapping []*Column) []interface{} {
+	values := make([]interface{}, len(mapping))
+
+	for index, column := range mapping {
+		if column != nil {
+			values[index] = t.field(column).Addr().Interface()
 		} else {
-			value = &sql.RawBytes{}
+			values[index] = &sql.RawBytes{}
 		}
+	}
+
+	return values
+}
+
+func (t *EntityContext) QueryRow(db Executor) error {
+	return t.QueryRowContext(context.Background(), db)
+}
 
-		values = append(values, value)
+// QueryRowContext reads the row with the primary key of the entity into the
+// model. A soft-deleted row is not found unless IncludeDeleted is set.
+func (t *EntityContext) QueryRowContext(ctx context.Context, db Executor) error {
+	if err := t.selectColumns(ctx, db, t.schema.Columns, t.withDeleted); err != nil {
+		return err
 	}
 
-	return scanner.Scan(values...)
+	t.takeSnapshot()
+	return t.afterScan(ctx, db)
 }
 
-func (t *EntityContext) QueryRow(db *sql.DB) error {
-	columns := []string{}
-	values := make([]interface{}, 0)
+// selectColumns reads the columns of the row with the primary key of the
+// entity into the model
+func (t *EntityContext) selectColumns(ctx context.Context, db Executor, columns []*Column, withDeleted bool) error {
+	d := t.Dialect()
+	names := []string{}
 
-	for _, column := range t.schema.Columns {
-		value := t.modelValue.Field(column.Index).Addr().Interface()
-		expression := fmt.Sprintf("%s = ?", column.Name)
+	for _, column := range columns {
+		names = append(names, column.Name)
+	}
 
-		if column.PrimaryKey {
-			columns = append(columns, expression)
-			values = append(values, value)
+	condition, values, err := t.primaryKey(1)
+	if err != nil {
+		return err
+	}
+
+	if !withDeleted {
+		condition = t.notDeleted(condition)
+	}
+
+	statement := fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(quoteAll(d, names), ","), d.Quote(t.schema.Table), condition)
+	row := db.QueryRowContext(ctx, statement, values...)
+	if err := row.Scan(t.scanValues(columns)...); err != nil {
+		return d.TranslateError(notFound(err))
+	}
+
+	return nil
+}
+
+func (t *EntityContext) Insert(db Executor) (int64, error) {
+	return t.InsertContext(context.Background(), db)
+}
+
+// InsertContext inserts the entity. The key generated for the auto-increment
+// column and the returning columns are read back into the model.
+func (t *EntityContext) InsertContext(ctx context.Context, db Executor) (int64, error) {
+	if err := t.beforeInsert(ctx, db); err != nil {
+		return 0, err
+	}
+
+	cnt, err := t.insert(ctx, db)
+	if err != nil {
+		return cnt, err
+	}
+
+	t.takeSnapshot()
+	return cnt, t.afterInsert(ctx, db)
+}
+
+func (t *EntityContext) insert(ctx context.Context, db Executor) (int64, error) {
+	d := t.Dialect()
+	columns, values, err := t.insertValues(t.metadata.now())
+	if err != nil {
+		return 0, err
+	}
+
+	statement := d.Insert(t.schema.Table, columns)
+
+	auto := t.autoIncrement()
+	returning, err := t.returningColumns(auto)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(returning) == 0 {
+		return execSQL(ctx, db, d, statement, values...)
+	}
+
+	if query := d.InsertReturning(t.schema.Table, columns, names(returning)); query != "" {
+		return t.queryReturning(ctx, db, query, values...)
+	}
+
+	result, err := db.ExecContext(ctx, statement, values...)
+	if err != nil {
+		return 0, d.TranslateError(err)
+	}
+
+	if auto != nil {
+		id, err := result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+
+		if err := setInt(t.field(auto), id); err != nil {
+			return 0, err
+		}
+
+		returning = returning[1:]
+	}
+
+	if len(returning) > 0 {
+		if err := t.selectColumns(ctx, db, returning, true); err != nil {
+			return 0, err
 		}
 	}
 
-	statement := fmt.Sprintf("SELECT * FROM %s WHERE %s", t.schema.Table, strings.Join(columns, ","))
-	row := db.QueryRow(statement, values...)
-	return t.Scan(&RowScanner{row})
+	return result.RowsAffected()
 }
 
-func (t *EntityContext) Insert(db *sql.DB) (int64, error) {
+func (t *EntityContext) autoIncrement() *Column {
+	for _, column := range t.schema.Columns {
+		if column.AutoIncrement {
+			return column
+		}
+	}
+	return nil
+}
+
+func (t *EntityContext) insertValues(now time.Time) ([]string, []interface{}, error) {
 	columns := []string{}
 	values := make([]interface{}, 0)
-	placeholders := []string{}
-	now := reflect.ValueOf(time.Now())
 
 	for _, column := range t.schema.Columns {
-		field := t.modelValue.Field(column.Index)
-		if column.Name == FieldCreatedAt || column.Name == FieldUpdatedAt {
-			field.Set(now)
+		if column.AutoIncrement || column.Generated {
+			continue
+		}
+
+		field := t.lookup(column)
+		if column.Created || column.Updated {
+			field = t.field(column)
+			if err := setTime(field, now, column.Millis); err != nil {
+				return nil, nil, err
+			}
 		}
 
-		value := field.Addr().Interface()
-		values = append(values, value)
+		if column.OmitEmpty && (!field.IsValid() || field.IsZero()) {
+			continue
+		}
+
+		values = append(values, bindValue(field))
 		columns = append(columns, column.Name)
-		placeholders = append(placeholders, "?")
 	}
 
-	statement := fmt.Sprintf("INSERT INTO %s (%s) VALUES(%s)", t.schema.Table, strings.Join(columns, ","), strings.Join(placeholders, ","))
-	return execSQL(db, statement, values...)
+	return columns, values, nil
 }
 
-func (t *EntityContext) Update(db *sql.DB, fields ...Fields) (int64, error) {
+func (t *EntityContext) Update(db Executor, fields ...Fields) (int64, error) {
+	return t.UpdateContext(context.Background(), db, fields...)
+}
+
+// UpdateContext updates the row of the entity. Without fields only the
+// columns changed since the values were last read or written by the entity
+// context are updated. When none changed the database is not hit.
+func (t *EntityContext) UpdateContext(ctx context.Context, db Executor, fields ...Fields) (int64, error) {
+	if err := t.beforeUpdate(ctx, db); err != nil {
+		return 0, err
+	}
+
+	cnt, err := t.update(ctx, db, fields)
+	if err != nil {
+		return cnt, err
+	}
+
+	t.takeSnapshot()
+	return cnt, t.afterUpdate(ctx, db)
+}
+
+func (t *EntityContext) update(ctx context.Context, db Executor, fields []Fields) (int64, error) {
 	columns := []string{}
 	values := make([]interface{}, 0)
-	conditionValues := make([]interface{}, 0)
-	conditions := []string{}
-	allFields, merged := mergeFields(fields)
-	now := reflect.ValueOf(time.Now())
+	now := t.metadata.now()
+
+	allFields, merged, err := t.resolveFields(fields)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := t.updatable(allFields); err != nil {
+		return 0, err
+	}
+
+	changes := t.Changes()
+	if !merged && changes != nil && !t.modified(changes) {
+		return 0, nil
+	}
 
 	for _, column := range t.schema.Columns {
-		field := t.modelValue.Field(column.Index)
-		if column.Name == FieldUpdatedAt {
-			field.Set(now)
+		field := t.lookup(column)
+		if column.Updated {
+			field = t.field(column)
+			if err := setTime(field, now, column.Millis); err != nil {
+				return 0, err
+			}
 		}
 
-		value := field.Addr().Interface()
-		expression := fmt.Sprintf("%s = ?", column.Name)
-
-		if column.PrimaryKey {
-			conditions = append(conditions, expression)
-			conditionValues = append(values, value)
+		if !column.updatable() {
 			continue
 		}
 
+		value := bindValue(field)
+
 		if merged {
-			ok := false
-			if value, ok = allFields[column.Name]; !ok {
+			if fieldValue, ok := allFields[column.Name]; ok {
+				value = fieldValue
+			} else if !column.Updated {
 				continue
 			}
+		} else if _, ok := changes[column.Name]; changes != nil && !ok && !column.Updated {
+			continue
+		}
+
+		assignment, args := t.assignments(column.Name, value, len(values)+1)
+		columns = append(columns, assignment)
+		values = append(values, args...)
+	}
+
+	version := t.version()
+	if version == nil {
+		// there is nothing to write when the table has only key columns
+		if len(columns) == 0 {
+			return 0, nil
+		}
+
+		condition, conditionValues, err := t.primaryKey(len(values) + 1)
+		if err != nil {
+			return 0, err
 		}
 
-		columns = append(columns, expression)
-		values = append(values, value)
+		return t.updateRow(ctx, db, columns, condition, append(values, conditionValues...))
+	}
+
+	current, err := intOf(t.lookup(version))
+	if err != nil {
+		return 0, err
+	}
+
+	columns = append(columns, t.assignment(version.Name, len(values)+1))
+	values = append(values, current+1)
+
+	condition, conditionValues, err := t.primaryKey(len(values) + 1)
+	if err != nil {
+		return 0, err
 	}
 
 	values = append(values, conditionValues...)
-	statement := fmt.Sprintf("UPDATE %s SET %s WHERE %s", t.schema.Table, strings.Join(columns, ","), strings.Join(conditions, ","))
-	return execSQL(db, statement, values...)
+	condition += " AND " + t.assignment(version.Name, len(values)+1)
+	values = append(values, current)
+
+	cnt, err := t.updateRow(ctx, db, columns, condition, values)
+	if err == ErrNoRowsAffected {
+		return cnt, ErrStaleEntity
+	}
+
+	if err != nil {
+		return cnt, err
+	}
+
+	return cnt, setInt(t.field(version), current+1)
 }
 
-func (t *EntityContext) Delete(db *sql.DB) (int64, error) {
-	columns := []string{}
-	values := make([]interface{}, 0)
+// modified reports whether the changes contain a column that is written by
+// Update and not managed by it
+func (t *EntityContext) modified(changes map[string]Change) bool {
+	for _, column := range t.schema.Columns {
+		if !column.updatable() || column.Updated {
+			continue
+		}
+
+		if _, ok := changes[column.Name]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// updateRow executes the UPDATE of the row matched by the condition and reads
+// the returning columns back
+func (t *EntityContext) updateRow(ctx context.Context, db Executor, columns []string, condition string, values []interface{}) (int64, error) {
+	d := t.Dialect()
+
+	returning, err := t.returningColumns(nil)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(returning) > 0 {
+		if query := d.UpdateReturning(t.schema.Table, columns, condition, names(returning)); query != "" {
+			return t.queryReturning(ctx, db, query, values...)
+		}
+	}
+
+	statement := fmt.Sprintf("UPDATE %s SET %s WHERE %s", d.Quote(t.schema.Table), strings.Join(columns, ","), condition)
+	cnt, err := execAffectingSQL(ctx, db, d, statement, values...)
+	if err != nil || len(returning) == 0 {
+		return cnt, err
+	}
+
+	return cnt, t.selectColumns(ctx, db, returning, true)
+}
+
+// version returns the column used for optimistic locking
+func (t *EntityContext) version() *Column {
+	for _, column := range t.schema.Columns {
+		if column.Version {
+			return column
+		}
+	}
+	return nil
+}
+
+// returningColumns returns the auto-increment column, when it is provided,
+// followed by the generated columns and the columns set by Returning
+func (t *EntityContext) returningColumns(auto *Column) ([]*Column, error) {
+	columns := []*Column{}
+	if auto != nil {
+		columns = append(columns, auto)
+	}
 
 	for _, column := range t.schema.Columns {
-		value := t.modelValue.Field(column.Index).Addr().Interface()
-		expression := fmt.Sprintf("%s = ?", column.Name)
+		if column.Generated && column != auto {
+			columns = append(columns, column)
+		}
+	}
+
+	for _, name := range t.returning {
+		column := t.schema.column(name)
+		if column == nil {
+			return nil, fmt.Errorf("Unknown column %q for table %q", name, t.schema.Table)
+		}
 
+		if column != auto && !column.Generated {
+			columns = append(columns, column)
+		}
+	}
+
+	return columns, nil
+}
+
+// queryReturning executes a statement that returns at most one row and scans
+// the row into the model
+func (t *EntityContext) queryReturning(ctx context.Context, db Executor, query string, values ...interface{}) (int64, error) {
+	d := t.Dialect()
+
+	rows, err := db.QueryContext(ctx, query, values...)
+	if err != nil {
+		return 0, d.TranslateError(err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, d.TranslateError(err)
+		}
+		return 0, ErrNoRowsAffected
+	}
+
+	if err := t.scan(rows); err != nil {
+		return 0, err
+	}
+
+	if err := rows.Close(); err != nil {
+		return 0, d.TranslateError(err)
+	}
+
+	return 1, nil
+}
+
+func (t *EntityContext) Upsert(db Executor, fields ...Fields) (int64, error) {
+	return t.UpsertContext(context.Background(), db, fields...)
+}
+
+// UpsertContext inserts the entity or updates the existing row that has the
+// same primary key or values of the unique index set by OnConflict. When
+// fields are provided only they are updated.
+func (t *EntityContext) UpsertContext(ctx context.Context, db Executor, fields ...Fields) (int64, error) {
+	keys, err := t.conflictKeys()
+	if err != nil {
+		return 0, err
+	}
+
+	updates := []string{}
+	allFields, merged, err := t.resolveFields(fields)
+	if err != nil {
+		return 0, err
+	}
+
+	columns, values, err := t.insertValues(t.metadata.now())
+	if err != nil {
+		return 0, err
+	}
+
+	for index, column := range columns {
+		definition := t.schema.column(column)
+
+		if value, ok := allFields[column]; ok {
+			if _, ok := value.(Expression); ok {
+				return 0, fmt.Errorf("Expression of column %q is not supported by Upsert", column)
+			}
+			values[index] = value
+		} else if merged && !definition.Updated {
+			continue
+		}
+
+		// the version of an existing row is not reset by the inserted value
+		if !contains(keys, column) && !definition.Created && !definition.Version {
+			updates = append(updates, column)
+		}
+	}
+
+	statement := t.Dialect().Upsert(t.schema.Table, columns, keys, updates)
+	return execSQL(ctx, db, t.Dialect(), statement, values...)
+}
+
+func (t *EntityContext) conflictKeys() ([]string, error) {
+	if t.conflictIndex == "" {
+		keys := []string{}
+		for _, column := range t.schema.Columns {
+			if column.PrimaryKey {
+				keys = append(keys, column.Name)
+			}
+		}
+
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("Table %q has no primary key", t.schema.Table)
+		}
+
+		return keys, nil
+	}
+
+	for _, index := range t.schema.Indexes {
+		if index.Name == t.conflictIndex && index.Unique {
+			return index.Columns, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Unique index %q not found", t.conflictIndex)
+}
+
+func (t *EntityContext) Delete(db Executor) (int64, error) {
+	return t.DeleteContext(context.Background(), db)
+}
+
+// DeleteContext deletes the row of the entity. When the table has a
+// deleted_at column the row is soft-deleted by setting it instead.
+func (t *EntityContext) DeleteContext(ctx context.Context, db Executor) (int64, error) {
+	if t.schema.column(FieldDeletedAt) == nil {
+		return t.HardDeleteContext(ctx, db)
+	}
+
+	return t.deleteWith(ctx, db, t.softDelete)
+}
+
+func (t *EntityContext) HardDelete(db Executor) (int64, error) {
+	return t.HardDeleteContext(context.Background(), db)
+}
+
+// HardDeleteContext deletes the row of the entity even when the table
+// supports soft delete
+func (t *EntityContext) HardDeleteContext(ctx context.Context, db Executor) (int64, error) {
+	return t.deleteWith(ctx, db, t.hardDelete)
+}
+
+// deleteWith runs the delete operation between the delete hooks
+func (t *EntityContext) deleteWith(ctx context.Context, db Executor, operation func(context.Context, Executor) (int64, error)) (int64, error) {
+	if err := t.beforeDelete(ctx, db); err != nil {
+		return 0, err
+	}
+
+	cnt, err := operation(ctx, db)
+	if err != nil {
+		return cnt, err
+	}
+
+	return cnt, t.afterDelete(ctx, db)
+}
+
+func (t *EntityContext) hardDelete(ctx context.Context, db Executor) (int64, error) {
+	d := t.Dialect()
+
+	condition, values, err := t.primaryKey(1)
+	if err != nil {
+		return 0, err
+	}
+
+	statement := fmt.Sprintf("DELETE FROM %s WHERE %s", d.Quote(t.schema.Table), condition)
+	return execAffectingSQL(ctx, db, d, statement, values...)
+}
+
+func (t *EntityContext) softDelete(ctx context.Context, db Executor) (int64, error) {
+	column := t.schema.column(FieldDeletedAt)
+	field := t.field(column)
+
+	if err := setTime(field, t.metadata.now(), column.Millis); err != nil {
+		return 0, err
+	}
+
+	return t.setDeletedAt(ctx, db, field.Interface(), t.notDeleted)
+}
+
+func (t *EntityContext) Restore(db Executor) (int64, error) {
+	return t.RestoreContext(context.Background(), db)
+}
+
+// RestoreContext clears the deleted_at column of the soft-deleted row of the
+// entity
+func (t *EntityContext) RestoreContext(ctx context.Context, db Executor) (int64, error) {
+	column := t.schema.column(FieldDeletedAt)
+	if column == nil {
+		return 0, fmt.Errorf("Table %q does not support soft delete", t.schema.Table)
+	}
+
+	cnt, err := t.setDeletedAt(ctx, db, nil, func(condition string) string { return condition })
+	if err != nil {
+		return cnt, err
+	}
+
+	if field := t.lookup(column); field.IsValid() {
+		field.Set(reflect.Zero(field.Type()))
+	}
+
+	return cnt, nil
+}
+
+// setDeletedAt sets the deleted_at column of the row with the primary key of
+// the entity whose condition is extended by scope
+func (t *EntityContext) setDeletedAt(ctx context.Context, db Executor, value interface{}, scope func(string) string) (int64, error) {
+	d := t.Dialect()
+
+	condition, values, err := t.primaryKey(2)
+	if err != nil {
+		return 0, err
+	}
+
+	statement := fmt.Sprintf("UPDATE %s SET %s WHERE %s", d.Quote(t.schema.Table), t.assignment(FieldDeletedAt, 1), scope(condition))
+	return execAffectingSQL(ctx, db, d, statement, append([]interface{}{value}, values...)...)
+}
+
+// notDeleted extends the condition joined with AND to exclude the
+// soft-deleted rows when the table supports soft delete
+func (t *EntityContext) notDeleted(condition string) string {
+	return notDeleted(t.Dialect(), t.schema, condition)
+}
+
+func notDeleted(d Dialect, schema *Schema, condition string) string {
+	if schema.column(FieldDeletedAt) == nil {
+		return condition
+	}
+
+	clause := fmt.Sprintf("%s IS NULL", d.Quote(FieldDeletedAt))
+	if condition == "" {
+		return clause
+	}
+
+	return fmt.Sprintf("%s AND %s", condition, clause)
+}
+
+// primaryKey returns the condition that matches the primary key columns,
+// whose placeholders start at position, and the values of the columns
+func (t *EntityContext) primaryKey(position int) (string, []interface{}, error) {
+	conditions := []string{}
+	values := make([]interface{}, 0)
+
+	for _, column := range t.schema.Columns {
 		if column.PrimaryKey {
-			columns = append(columns, expression)
-			values = append(values, value)
+			conditions = append(conditions, t.assignment(column.Name, position+len(values)))
+			values = append(values, bindValue(t.lookup(column)))
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "", nil, fmt.Errorf("Table %q has no primary key", t.schema.Table)
+	}
+
+	return strings.Join(conditions, " AND "), values, nil
+}
+
+// lookup returns the struct field of the column or the zero Value when a
+// pointer to an embedded struct on the way is nil. Unlike field it does not
+// allocate and is used to read the values.
+func (t *EntityContext) lookup(column *Column) reflect.Value {
+	v := t.modelValue
+
+	for position, index := range column.Index {
+		if position > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
 		}
+
+		v = v.Field(index)
+	}
+
+	return v
+}
+
+// bindValue returns the pointer to the field that is bound to a statement or
+// nil, which binds NULL, when the field does not exist
+func bindValue(field reflect.Value) interface{} {
+	if !field.IsValid() {
+		return nil
 	}
 
-	statement := fmt.Sprintf("DELETE FROM %s WHERE %s", t.schema.Table, strings.Join(columns, ","))
-	return execSQL(db, statement, values...)
+	return field.Addr().Interface()
+}
+
+// field returns the struct field of the column allocating the nil pointers
+// to embedded structs on the way. It is used to write the values.
+func (t *EntityContext) field(column *Column) reflect.Value {
+	v := t.modelValue
+
+	for position, index := range column.Index {
+		if position > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+
+		v = v.Field(index)
+	}
+
+	return v
+}
+
+// setTime sets the time field, which may be a pointer, sql.NullTime or an
+// int64 that stores Unix time in seconds or milliseconds
+func setTime(field reflect.Value, value time.Time, millis bool) error {
+	switch field.Interface().(type) {
+	case time.Time:
+		field.Set(reflect.ValueOf(value))
+	case *time.Time:
+		field.Set(reflect.ValueOf(&value))
+	case sql.NullTime:
+		field.Set(reflect.ValueOf(sql.NullTime{Time: value, Valid: true}))
+	default:
+		if field.Kind() == reflect.Ptr && field.Type().Elem().Kind() == reflect.Int64 {
+			field.Set(reflect.New(field.Type().Elem()))
+			field = field.Elem()
+		}
+
+		if field.Kind() != reflect.Int64 {
+			return fmt.Errorf("Cannot set time to field of type %s", field.Type())
+		}
+
+		if millis {
+			field.SetInt(value.UnixMilli())
+		} else {
+			field.SetInt(value.Unix())
+		}
+	}
+
+	return nil
+}
+
+// timeValue returns the value of the timestamp column set to value
+func timeValue(column *Column, value time.Time) (interface{}, error) {
+	field := reflect.New(column.Type).Elem()
+	if err := setTime(field, value, column.Millis); err != nil {
+		return nil, err
+	}
+
+	return field.Interface(), nil
+}
+
+// intOf returns the value of the integer field, which may be a pointer
+func intOf(field reflect.Value) (int64, error) {
+	field = reflect.Indirect(field)
+
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return field.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(field.Uint()), nil
+	case reflect.Invalid:
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("Must be integer field; got %s", field.Type())
+	}
+}
+
+// setInt sets the integer field, allocating it when it is a pointer
+func setInt(field reflect.Value, value int64) error {
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		field = field.Elem()
+	}
+
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		field.SetInt(value)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		field.SetUint(uint64(value))
+	default:
+		return fmt.Errorf("Cannot set generated key %d to field of type %s", value, field.Type())
+	}
+
+	return nil
+}
+
+func (t *EntityContext) assignment(column string, position int) string {
+	d := t.Dialect()
+	return fmt.Sprintf("%s = %s", d.Quote(column), d.Placeholder(position))
+}
+
+func names(columns []*Column) []string {
+	items := make([]string, len(columns))
+	for index, column := range columns {
+		items[index] = column.Name
+	}
+	return items
+}
+
+func placeholders(d Dialect, position, count int) string {
+	items := make([]string, count)
+	for index := range items {
+		items[index] = d.Placeholder(position + index)
+	}
+	return fmt.Sprintf("(%s)", strings.Join(items, ","))
 }
@@ -1,6 +1,8 @@
 package sqlutil_test
 
 import (
+	"context"
+	"database/sql"
 	"time"
 
 	"github.com/phogolabs/sqlutil"
@@ -136,6 +138,50 @@ var _ = Describe("Entity", func() {
 		})
 	})
 
+	Context("when the entity is upserted", func() {
+		It("inserts the missing row", func() {
+			cnt, err := sqlutil.NewEntityContext(&student{ID: "1234", Name: "Jack"}).Upsert(db)
+			Expect(cnt).To(Equal(int64(1)))
+			Expect(err).To(BeNil())
+
+			record := &student{ID: "1234"}
+			Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+			Expect(record.Name).To(Equal("Jack"))
+		})
+
+		It("updates the existing row", func() {
+			s := &student{ID: "1234", Name: "Jack"}
+			_, err := sqlutil.Insert(db, s)
+			Expect(err).To(BeNil())
+
+			cnt, err := sqlutil.Upsert(db, &student{ID: "1234", Name: "John"})
+			Expect(cnt).To(Equal(int64(1)))
+			Expect(err).To(BeNil())
+
+			record := &student{ID: "1234"}
+			Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+			Expect(record.Name).To(Equal("John"))
+			Expect(record.CreatedAt).To(BeTemporally("==", s.CreatedAt))
+		})
+
+		It("updates only the provided fields", func() {
+			_, err := sqlutil.Insert(db, &student{ID: "1234", Name: "Jack"})
+			Expect(err).To(BeNil())
+
+			_, err = sqlutil.Upsert(db, &student{ID: "1234", Name: "John"}, sqlutil.Fields{"updated_at": time.Now()})
+			Expect(err).To(BeNil())
+
+			record := &student{ID: "1234"}
+			Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+			Expect(record.Name).To(Equal("Jack"))
+		})
+
+		It("returns an error when the conflict index does not exist", func() {
+			_, err := sqlutil.NewEntityContext(&student{ID: "1234"}).OnConflict("unknown").Upsert(db)
+			Expect(err).To(MatchError(`Unique index "unknown" not found`))
+		})
+	})
+
 	It("deletes row correctly", func() {
 		cnt, err := sqlutil.NewEntityContext(&student{
 			ID:   "1234",
@@ -161,6 +207,744 @@ var _ = Describe("Entity", func() {
 		Expect(rows.Next()).To(BeFalse())
 	})
 
+	Context("when the context is canceled", func() {
+		var ctx context.Context
+
+		BeforeEach(func() {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithCancel(context.Background())
+			cancel()
+		})
+
+		It("does not execute the operations", func() {
+			entity := sqlutil.NewEntityContext(&student{ID: "1234", Name: "Jack"})
+
+			_, err := entity.InsertContext(ctx, db)
+			Expect(err).To(MatchError(context.Canceled))
+
+			_, err = entity.UpdateContext(ctx, db)
+			Expect(err).To(MatchError(context.Canceled))
+
+			_, err = entity.DeleteContext(ctx, db)
+			Expect(err).To(MatchError(context.Canceled))
+
+			Expect(entity.QueryRowContext(ctx, db)).To(MatchError(context.Canceled))
+			Expect(sqlutil.CreateTableContext(ctx, db, &student{})).To(MatchError(context.Canceled))
+		})
+
+		It("does not execute the package level operations", func() {
+			s := &student{ID: "1234", Name: "Jack"}
+
+			_, err := sqlutil.InsertContext(ctx, db, s)
+			Expect(err).To(MatchError(context.Canceled))
+
+			_, err = sqlutil.UpdateContext(ctx, db, s)
+			Expect(err).To(MatchError(context.Canceled))
+
+			_, err = sqlutil.DeleteContext(ctx, db, s)
+			Expect(err).To(MatchError(context.Canceled))
+
+			Expect(sqlutil.QueryRowContext(ctx, db, s)).To(MatchError(context.Canceled))
+		})
+	})
+
+	Context("when the primary key is composite", func() {
+		type enrollment struct {
+			StudentID string `sql:"student_id,varchar(50),pk"`
+			Grade     int    `sql:"grade,integer"`
+			CourseID  string `sql:"course_id,varchar(50),pk"`
+		}
+
+		BeforeEach(func() {
+			Expect(sqlutil.CreateTable(db, &enrollment{})).To(Succeed())
+
+			for _, course := range []string{"math", "art"} {
+				_, err := sqlutil.Insert(db, &enrollment{StudentID: "1", CourseID: course, Grade: 3})
+				Expect(err).To(BeNil())
+			}
+		})
+
+		AfterEach(func() {
+			_, err := db.Exec("drop table enrollment")
+			Expect(err).To(BeNil())
+		})
+
+		grade := func(course string) int {
+			e := &enrollment{StudentID: "1", CourseID: course}
+			Expect(sqlutil.QueryRow(db, e)).To(Succeed())
+			return e.Grade
+		}
+
+		It("queries the row by all key columns", func() {
+			e := &enrollment{StudentID: "1", CourseID: "art"}
+			Expect(sqlutil.QueryRow(db, e)).To(Succeed())
+			Expect(e.Grade).To(Equal(3))
+
+			Expect(sqlutil.QueryRow(db, &enrollment{StudentID: "1", CourseID: "music"})).To(MatchError(sql.ErrNoRows))
+		})
+
+		It("updates only the matching row", func() {
+			cnt, err := sqlutil.Update(db, &enrollment{StudentID: "1", CourseID: "art", Grade: 6})
+			Expect(err).To(BeNil())
+			Expect(cnt).To(Equal(int64(1)))
+
+			Expect(grade("art")).To(Equal(6))
+			Expect(grade("math")).To(Equal(3))
+		})
+
+		It("updates only the matching row with the provided fields", func() {
+			cnt, err := sqlutil.Update(db, &enrollment{StudentID: "1", CourseID: "math"}, sqlutil.Fields{"grade": 5})
+			Expect(err).To(BeNil())
+			Expect(cnt).To(Equal(int64(1)))
+
+			Expect(grade("art")).To(Equal(3))
+			Expect(grade("math")).To(Equal(5))
+		})
+
+		It("does not update a table that has only key columns", func() {
+			type membership struct {
+				StudentID string `sql:"student_id,varchar(50),pk"`
+				CourseID  string `sql:"course_id,varchar(50),pk"`
+			}
+
+			Expect(sqlutil.CreateTable(db, &membership{})).To(Succeed())
+			defer db.Exec("drop table membership")
+
+			_, err := sqlutil.Insert(db, &membership{StudentID: "1", CourseID: "math"})
+			Expect(err).To(BeNil())
+
+			cnt, err := sqlutil.Update(db, &membership{StudentID: "1", CourseID: "math"})
+			Expect(err).To(BeNil())
+			Expect(cnt).To(BeZero())
+		})
+
+		It("deletes only the matching row", func() {
+			cnt, err := sqlutil.Delete(db, &enrollment{StudentID: "1", CourseID: "math"})
+			Expect(err).To(BeNil())
+			Expect(cnt).To(Equal(int64(1)))
+
+			Expect(grade("art")).To(Equal(3))
+			Expect(sqlutil.QueryRow(db, &enrollment{StudentID: "1", CourseID: "math"})).To(MatchError(sql.ErrNoRows))
+		})
+
+		It("generates conditions joined with AND", func() {
+			recordDB, err := sql.Open("sqlutil-recorder", "")
+			Expect(err).To(BeNil())
+			defer recordDB.Close()
+			recorder.Reset()
+
+			_, err = sqlutil.Update(recordDB, &enrollment{StudentID: "1", CourseID: "math"})
+			Expect(err).To(BeNil())
+			_, err = sqlutil.Delete(recordDB, &enrollment{StudentID: "1", CourseID: "math"})
+			Expect(err).To(BeNil())
+
+			Expect(recorder.statements).To(Equal([]string{
+				`UPDATE "enrollment" SET "grade" = ? WHERE "student_id" = ? AND "course_id" = ?`,
+				`DELETE FROM "enrollment" WHERE "student_id" = ? AND "course_id" = ?`,
+			}))
+		})
+	})
+
+	Context("when the entity has nested structs", func() {
+		type Timestamps struct {
+			CreatedAt time.Time `sql:"created_at,timestamp,not_null"`
+			UpdatedAt time.Time `sql:"updated_at,timestamp,not_null"`
+		}
+
+		type Address struct {
+			Street string `sql:"street,text"`
+			City   string `sql:"city,text"`
+		}
+
+		type person struct {
+			ID string `sql:"id,varchar(50),pk"`
+			*Timestamps
+			Address Address `sqlprefix:"address_"`
+		}
+
+		BeforeEach(func() {
+			Expect(sqlutil.CreateTable(db, &person{})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			_, err := db.Exec("drop table person")
+			Expect(err).To(BeNil())
+		})
+
+		It("reads and writes the nested fields", func() {
+			p := &person{ID: "1", Address: Address{Street: "Main", City: "Sofia"}}
+			_, err := sqlutil.Insert(db, p)
+			Expect(err).To(BeNil())
+			Expect(p.Timestamps).NotTo(BeNil())
+			Expect(p.CreatedAt).NotTo(Equal(time.Time{}))
+
+			p.Address.City = "Plovdiv"
+			_, err = sqlutil.Update(db, p)
+			Expect(err).To(BeNil())
+
+			record := &person{ID: "1"}
+			Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+			Expect(record.Address).To(Equal(Address{Street: "Main", City: "Plovdiv"}))
+			Expect(record.CreatedAt).To(BeTemporally("==", p.CreatedAt))
+
+			people := []person{}
+			Expect(sqlutil.Select(db, &people, "SELECT address_city, id FROM person")).To(Succeed())
+			Expect(people).To(HaveLen(1))
+			Expect(people[0].Address.City).To(Equal("Plovdiv"))
+		})
+
+		It("binds NULL for the fields of a nil embedded struct without allocating it", func() {
+			type Contact struct {
+				Phone *string `sql:"phone,text"`
+			}
+
+			type customer struct {
+				ID string `sql:"id,varchar(50),pk"`
+				*Contact
+			}
+
+			Expect(sqlutil.CreateTable(db, &customer{})).To(Succeed())
+			defer db.Exec("drop table customer")
+
+			c := &customer{ID: "1"}
+			_, err := sqlutil.Insert(db, c)
+			Expect(err).To(BeNil())
+			Expect(c.Contact).To(BeNil())
+
+			entity := sqlutil.NewEntityContext(c)
+			Expect(entity.QueryRow(db)).To(Succeed())
+			Expect(c.Contact).NotTo(BeNil())
+			Expect(c.Phone).To(BeNil())
+
+			c.Contact = nil
+			Expect(entity.Changes()).To(BeEmpty())
+
+			_, err = sqlutil.Update(db, c)
+			Expect(err).To(BeNil())
+			Expect(c.Contact).To(BeNil())
+		})
+	})
+
+	Context("when the entity has nullable and omitempty fields", func() {
+		type profile struct {
+			ID       string     `sql:"id,varchar(50),pk"`
+			Nickname *string    `sql:"nickname,text"`
+			Age      *int64     `sql:"age,integer"`
+			Birthday *time.Time `sql:"birthday,timestamp"`
+			Status   string     `sql:"status,text,omitempty"`
+		}
+
+		BeforeEach(func() {
+			_, err := db.Exec("CREATE TABLE profile (id varchar(50) PRIMARY KEY, nickname text, age integer, birthday timestamp, status text NOT NULL DEFAULT 'active')")
+			Expect(err).To(BeNil())
+		})
+
+		AfterEach(func() {
+			_, err := db.Exec("drop table profile")
+			Expect(err).To(BeNil())
+		})
+
+		It("writes and reads NULL for nil pointers", func() {
+			_, err := sqlutil.Insert(db, &profile{ID: "1", Status: "new"})
+			Expect(err).To(BeNil())
+
+			var nickname sql.NullString
+			Expect(db.QueryRow("SELECT nickname FROM profile WHERE id = '1'").Scan(&nickname)).To(Succeed())
+			Expect(nickname.Valid).To(BeFalse())
+
+			record := &profile{ID: "1", Age: new(int64)}
+			Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+			Expect(record.Nickname).To(BeNil())
+			Expect(record.Age).To(BeNil())
+			Expect(record.Birthday).To(BeNil())
+		})
+
+		It("writes and reads the values of set pointers", func() {
+			name := "jack"
+			age := int64(42)
+
+			_, err := sqlutil.Insert(db, &profile{ID: "1", Nickname: &name, Age: &age})
+			Expect(err).To(BeNil())
+
+			record := &profile{ID: "1"}
+			Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+			Expect(record.Nickname).To(Equal(&name))
+			Expect(record.Age).To(Equal(&age))
+
+			record.Age = nil
+			_, err = sqlutil.Update(db, record)
+			Expect(err).To(BeNil())
+
+			Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+			Expect(record.Age).To(BeNil())
+			Expect(record.Nickname).To(Equal(&name))
+		})
+
+		It("omits the empty omitempty columns so the default applies", func() {
+			_, err := sqlutil.Insert(db, &profile{ID: "1"})
+			Expect(err).To(BeNil())
+
+			_, err = sqlutil.Insert(db, &profile{ID: "2", Status: "blocked"})
+			Expect(err).To(BeNil())
+
+			record := &profile{ID: "1"}
+			Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+			Expect(record.Status).To(Equal("active"))
+
+			record = &profile{ID: "2"}
+			Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+			Expect(record.Status).To(Equal("blocked"))
+		})
+
+		It("inserts the rows with different omitted columns in separate statements", func() {
+			profiles := []*profile{{ID: "1"}, {ID: "2"}, {ID: "3", Status: "blocked"}}
+
+			cnt, err := sqlutil.InsertAll(db, profiles)
+			Expect(err).To(BeNil())
+			Expect(cnt).To(Equal(int64(3)))
+
+			record := &profile{ID: "2"}
+			Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+			Expect(record.Status).To(Equal("active"))
+
+			record = &profile{ID: "3"}
+			Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+			Expect(record.Status).To(Equal("blocked"))
+		})
+	})
+
+	Context("when the primary key is auto-increment", func() {
+		type ticket struct {
+			ID    int    `sql:"id,integer,pk,autoincrement"`
+			Title string `sql:"title,text"`
+		}
+
+		BeforeEach(func() {
+			Expect(sqlutil.CreateTable(db, &ticket{})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			_, err := db.Exec("drop table ticket")
+			Expect(err).To(BeNil())
+		})
+
+		It("writes the generated key back into the model", func() {
+			first := &ticket{Title: "first"}
+			cnt, err := sqlutil.Insert(db, first)
+			Expect(err).To(BeNil())
+			Expect(cnt).To(Equal(int64(1)))
+			Expect(first.ID).To(Equal(1))
+
+			second := &ticket{ID: 100, Title: "second"}
+			_, err = sqlutil.Insert(db, second)
+			Expect(err).To(BeNil())
+			Expect(second.ID).To(Equal(2))
+
+			second.Title = "updated"
+			_, err = sqlutil.Update(db, second)
+			Expect(err).To(BeNil())
+
+			record := &ticket{ID: 2}
+			Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+			Expect(record.Title).To(Equal("updated"))
+		})
+
+		It("omits the key from the batch insert", func() {
+			cnt, err := sqlutil.InsertAll(db, []*ticket{{Title: "first"}, {Title: "second"}})
+			Expect(err).To(BeNil())
+			Expect(cnt).To(Equal(int64(2)))
+
+			record := &ticket{ID: 2}
+			Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+			Expect(record.Title).To(Equal("second"))
+		})
+	})
+
+	Context("when the entity has generated columns", func() {
+		type document struct {
+			ID     string `sql:"id,varchar(50),pk"`
+			Title  string `sql:"title,text"`
+			Slug   string `sql:"slug,text,generated"`
+			Status string `sql:"status,text,omitempty"`
+		}
+
+		BeforeEach(func() {
+			_, err := db.Exec("CREATE TABLE document (id varchar(50) PRIMARY KEY, title text, slug text GENERATED ALWAYS AS (lower(title)) VIRTUAL, status text DEFAULT 'draft')")
+			Expect(err).To(BeNil())
+		})
+
+		AfterEach(func() {
+			_, err := db.Exec("drop table document")
+			Expect(err).To(BeNil())
+		})
+
+		It("reads the generated columns back after insert and update", func() {
+			doc := &document{ID: "1", Title: "Hello", Slug: "ignored"}
+			cnt, err := sqlutil.Insert(db, doc)
+			Expect(err).To(BeNil())
+			Expect(cnt).To(Equal(int64(1)))
+			Expect(doc.Slug).To(Equal("hello"))
+			Expect(doc.Status).To(BeEmpty())
+
+			doc.Title = "World"
+			cnt, err = sqlutil.Update(db, doc)
+			Expect(err).To(BeNil())
+			Expect(cnt).To(Equal(int64(1)))
+			Expect(doc.Slug).To(Equal("world"))
+		})
+
+		It("reads the columns set by Returning back", func() {
+			doc := &document{ID: "1", Title: "Hello"}
+			_, err := sqlutil.NewEntityContext(doc).Returning("status").Insert(db)
+			Expect(err).To(BeNil())
+			Expect(doc.Status).To(Equal("draft"))
+			Expect(doc.Slug).To(Equal("hello"))
+		})
+
+		It("returns an error when the returning column is unknown", func() {
+			_, err := sqlutil.NewEntityContext(&document{ID: "1"}).Returning("unknown").Insert(db)
+			Expect(err).To(MatchError(`Unknown column "unknown" for table "document"`))
+		})
+
+		It("returns an error when the updated row does not exist", func() {
+			_, err := sqlutil.Update(db, &document{ID: "1"})
+			Expect(err).To(MatchError(sqlutil.ErrNoRowsAffected))
+		})
+	})
+
+	Context("when the table supports soft delete", func() {
+		type post struct {
+			ID        string     `sql:"id,varchar(50),pk"`
+			Title     string     `sql:"title,text"`
+			DeletedAt *time.Time `sql:"deleted_at,timestamp"`
+		}
+
+		BeforeEach(func() {
+			Expect(sqlutil.CreateTable(db, &post{})).To(Succeed())
+			_, err := sqlutil.Insert(db, &post{ID: "1", Title: "Hello"})
+			Expect(err).To(BeNil())
+		})
+
+		AfterEach(func() {
+			_, err := db.Exec("drop table post")
+			Expect(err).To(BeNil())
+		})
+
+		count := func() int64 {
+			var cnt int64
+			Expect(db.QueryRow("SELECT count(*) FROM post").Scan(&cnt)).To(Succeed())
+			return cnt
+		}
+
+		It("sets the deleted_at column instead of deleting the row", func() {
+			p := &post{ID: "1"}
+			cnt, err := sqlutil.Delete(db, p)
+			Expect(err).To(BeNil())
+			Expect(cnt).To(Equal(int64(1)))
+			Expect(p.DeletedAt).NotTo(BeNil())
+			Expect(count()).To(Equal(int64(1)))
+
+			_, err = sqlutil.Delete(db, &post{ID: "1"})
+			Expect(err).To(MatchError(sqlutil.ErrNoRowsAffected))
+		})
+
+		It("does not find the soft-deleted row by default", func() {
+			_, err := sqlutil.Delete(db, &post{ID: "1"})
+			Expect(err).To(BeNil())
+
+			Expect(sqlutil.QueryRow(db, &post{ID: "1"})).To(MatchError(sqlutil.ErrNotFound))
+
+			p := &post{ID: "1"}
+			Expect(sqlutil.NewEntityContext(p).IncludeDeleted().QueryRow(db)).To(Succeed())
+			Expect(p.Title).To(Equal("Hello"))
+			Expect(p.DeletedAt).NotTo(BeNil())
+		})
+
+		It("restores the soft-deleted row", func() {
+			p := &post{ID: "1"}
+			_, err := sqlutil.Delete(db, p)
+			Expect(err).To(BeNil())
+
+			cnt, err := sqlutil.NewEntityContext(p).Restore(db)
+			Expect(err).To(BeNil())
+			Expect(cnt).To(Equal(int64(1)))
+			Expect(p.DeletedAt).To(BeNil())
+
+			Expect(sqlutil.QueryRow(db, &post{ID: "1"})).To(Succeed())
+		})
+
+		It("deletes the row permanently", func() {
+			_, err := sqlutil.Delete(db, &post{ID: "1"})
+			Expect(err).To(BeNil())
+
+			cnt, err := sqlutil.NewEntityContext(&post{ID: "1"}).HardDelete(db)
+			Expect(err).To(BeNil())
+			Expect(cnt).To(Equal(int64(1)))
+			Expect(count()).To(BeZero())
+		})
+
+		It("returns an error when a table without soft delete is restored", func() {
+			_, err := sqlutil.NewEntityContext(&student{ID: "1"}).Restore(db)
+			Expect(err).To(MatchError(`Table "student" does not support soft delete`))
+		})
+
+		It("stores the soft delete timestamp in milliseconds", func() {
+			type note struct {
+				ID        string `sql:"id,varchar(50),pk"`
+				DeletedAt *int64 `sql:"deleted_at,bigint,millis"`
+			}
+
+			now := time.Date(2020, 1, 2, 3, 4, 5, 6000000, time.UTC)
+			sqlutil.SetClock(sqlutil.ClockFunc(func() time.Time { return now }))
+			defer sqlutil.SetClock(nil)
+
+			Expect(sqlutil.CreateTable(db, &note{})).To(Succeed())
+			defer db.Exec("drop table note")
+
+			_, err := sqlutil.Insert(db, &note{ID: "1"})
+			Expect(err).To(BeNil())
+
+			n := &note{ID: "1"}
+			_, err = sqlutil.Delete(db, n)
+			Expect(err).To(BeNil())
+			Expect(n.DeletedAt).To(Equal(&[]int64{now.UnixMilli()}[0]))
+
+			var stored int64
+			Expect(db.QueryRow("SELECT deleted_at FROM note").Scan(&stored)).To(Succeed())
+			Expect(stored).To(Equal(now.UnixMilli()))
+		})
+	})
+
+	Context("when the entity has a version column", func() {
+		type purchase struct {
+			ID      string `sql:"id,varchar(50),pk"`
+			Status  string `sql:"status,text"`
+			Version int    `sql:"version,integer,version"`
+		}
+
+		BeforeEach(func() {
+			Expect(sqlutil.CreateTable(db, &purchase{})).To(Succeed())
+			_, err := sqlutil.Insert(db, &purchase{ID: "1", Status: "new", Version: 1})
+			Expect(err).To(BeNil())
+		})
+
+		AfterEach(func() {
+			_, err := db.Exec("drop table purchase")
+			Expect(err).To(BeNil())
+		})
+
+		It("increments the version", func() {
+			o := &purchase{ID: "1"}
+			Expect(sqlutil.QueryRow(db, o)).To(Succeed())
+
+			o.Status = "paid"
+			cnt, err := sqlutil.Update(db, o)
+			Expect(err).To(BeNil())
+			Expect(cnt).To(Equal(int64(1)))
+			Expect(o.Version).To(Equal(2))
+
+			_, err = sqlutil.Update(db, o, sqlutil.Fields{"status": "shipped"})
+			Expect(err).To(BeNil())
+			Expect(o.Version).To(Equal(3))
+
+			record := &purchase{ID: "1"}
+			Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+			Expect(record).To(Equal(&purchase{ID: "1", Status: "shipped", Version: 3}))
+		})
+
+		It("returns an error when the entity is stale", func() {
+			first := &purchase{ID: "1"}
+			Expect(sqlutil.QueryRow(db, first)).To(Succeed())
+
+			second := &purchase{ID: "1"}
+			Expect(sqlutil.QueryRow(db, second)).To(Succeed())
+
+			first.Status = "paid"
+			_, err := sqlutil.Update(db, first)
+			Expect(err).To(BeNil())
+
+			second.Status = "canceled"
+			cnt, err := sqlutil.Update(db, second)
+			Expect(err).To(MatchError(sqlutil.ErrStaleEntity))
+			Expect(cnt).To(BeZero())
+			Expect(second.Version).To(Equal(1))
+
+			record := &purchase{ID: "1"}
+			Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+			Expect(record.Status).To(Equal("paid"))
+		})
+
+		It("keeps the version of the existing row on upsert", func() {
+			_, err := sqlutil.Upsert(db, &purchase{ID: "1", Status: "paid"})
+			Expect(err).To(BeNil())
+
+			record := &purchase{ID: "1"}
+			Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+			Expect(record.Status).To(Equal("paid"))
+			Expect(record.Version).To(Equal(1))
+		})
+	})
+
+	Context("when the entity has audit timestamps", func() {
+		type event struct {
+			ID         string     `sql:"id,varchar(50),pk"`
+			Name       string     `sql:"name,text"`
+			CreatedAt  int64      `sql:"created_at,bigint"`
+			ModifiedAt *time.Time `sql:"modified_at,timestamp,updated"`
+			InsertedOn int64      `sql:"inserted_on,bigint,created,millis"`
+		}
+
+		type legacy struct {
+			ID        string `sql:"id,varchar(50),pk"`
+			CreatedAt string `sql:"created_at,text"`
+		}
+
+		now := time.Date(2020, 5, 1, 10, 0, 0, 0, time.FixedZone("EEST", 3*60*60))
+
+		BeforeEach(func() {
+			sqlutil.SetClock(sqlutil.ClockFunc(func() time.Time { return now }))
+			Expect(sqlutil.CreateTable(db, &event{})).To(Succeed())
+			Expect(sqlutil.CreateTable(db, &legacy{})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			sqlutil.SetClock(nil)
+			_, err := db.Exec("drop table event")
+			Expect(err).To(BeNil())
+			_, err = db.Exec("drop table legacy")
+			Expect(err).To(BeNil())
+		})
+
+		It("sets the timestamps from the clock in UTC", func() {
+			e := &event{ID: "1"}
+			_, err := sqlutil.Insert(db, e)
+			Expect(err).To(BeNil())
+			Expect(e.CreatedAt).To(Equal(now.Unix()))
+			Expect(e.InsertedOn).To(Equal(now.UnixMilli()))
+			Expect(e.ModifiedAt).NotTo(BeNil())
+			Expect(*e.ModifiedAt).To(Equal(now.UTC()))
+			Expect(e.ModifiedAt.Location()).To(Equal(time.UTC))
+
+			later := now.Add(time.Hour)
+			sqlutil.SetClock(sqlutil.ClockFunc(func() time.Time { return later }))
+
+			e.Name = "changed"
+			_, err = sqlutil.Update(db, e)
+			Expect(err).To(BeNil())
+			Expect(e.CreatedAt).To(Equal(now.Unix()))
+			Expect(*e.ModifiedAt).To(Equal(later.UTC()))
+
+			record := &event{ID: "1"}
+			Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+			Expect(record.CreatedAt).To(Equal(now.Unix()))
+			Expect(record.InsertedOn).To(Equal(now.UnixMilli()))
+			Expect(record.ModifiedAt.Equal(later)).To(BeTrue())
+		})
+
+		It("sets the updated timestamp when only the given fields are updated", func() {
+			_, err := sqlutil.Insert(db, &event{ID: "1"})
+			Expect(err).To(BeNil())
+
+			later := now.Add(time.Hour)
+			sqlutil.SetClock(sqlutil.ClockFunc(func() time.Time { return later }))
+
+			e := &event{ID: "1"}
+			_, err = sqlutil.Update(db, e, sqlutil.Fields{"name": "changed"})
+			Expect(err).To(BeNil())
+			Expect(*e.ModifiedAt).To(Equal(later.UTC()))
+
+			record := &event{ID: "1"}
+			Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+			Expect(record.Name).To(Equal("changed"))
+			Expect(record.ModifiedAt.Equal(later)).To(BeTrue())
+		})
+
+		It("sets the updated timestamp when only the given fields are upserted", func() {
+			_, err := sqlutil.Insert(db, &event{ID: "1"})
+			Expect(err).To(BeNil())
+
+			later := now.Add(time.Hour)
+			sqlutil.SetClock(sqlutil.ClockFunc(func() time.Time { return later }))
+
+			_, err = sqlutil.Upsert(db, &event{ID: "1"}, sqlutil.Fields{"name": "changed"})
+			Expect(err).To(BeNil())
+
+			record := &event{ID: "1"}
+			Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+			Expect(record.Name).To(Equal("changed"))
+			Expect(record.ModifiedAt.Equal(later)).To(BeTrue())
+			Expect(record.CreatedAt).To(Equal(now.Unix()))
+		})
+
+		It("sets the updated timestamp of the rows updated by a query", func() {
+			_, err := sqlutil.Insert(db, &event{ID: "1"})
+			Expect(err).To(BeNil())
+
+			later := now.Add(time.Hour)
+			sqlutil.SetClock(sqlutil.ClockFunc(func() time.Time { return later }))
+
+			_, err = sqlutil.UpdateWhere(db, &event{}, sqlutil.Where("id", sqlutil.Eq, "1"), sqlutil.Fields{"name": "changed"})
+			Expect(err).To(BeNil())
+
+			record := &event{ID: "1"}
+			Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+			Expect(record.ModifiedAt.Equal(later)).To(BeTrue())
+		})
+
+		It("does not treat a conventional column of other type as timestamp", func() {
+			l := &legacy{ID: "1", CreatedAt: "yesterday"}
+			_, err := sqlutil.Insert(db, l)
+			Expect(err).To(BeNil())
+			Expect(l.CreatedAt).To(Equal("yesterday"))
+		})
+	})
+
+	Context("when the table name is schema-qualified", func() {
+		type userAccount struct {
+			_    struct{} `sqltable:"main.user_accounts"`
+			ID   string   `sql:"id,varchar(50),pk"`
+			Name string   `sql:"name,text" sqlindex:"user_accounts_name"`
+		}
+
+		BeforeEach(func() {
+			Expect(sqlutil.CreateTable(db, &userAccount{})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			_, err := db.Exec("drop table user_accounts")
+			Expect(err).To(BeNil())
+		})
+
+		It("uses the table name in all statements", func() {
+			_, err := sqlutil.Insert(db, &userAccount{ID: "1", Name: "Jack"})
+			Expect(err).To(BeNil())
+
+			_, err = sqlutil.Update(db, &userAccount{ID: "1", Name: "John"})
+			Expect(err).To(BeNil())
+
+			account := &userAccount{ID: "1"}
+			Expect(sqlutil.QueryRow(db, account)).To(Succeed())
+			Expect(account.Name).To(Equal("John"))
+
+			_, err = sqlutil.Delete(db, account)
+			Expect(err).To(BeNil())
+		})
+	})
+
+	Context("when the entity has no primary key", func() {
+		type log struct {
+			Message string `sql:"message,text"`
+		}
+
+		It("returns an error", func() {
+			_, err := sqlutil.Delete(db, &log{})
+			Expect(err).To(MatchError(`Table "log" has no primary key`))
+
+			_, err = sqlutil.Upsert(db, &log{})
+			Expect(err).To(MatchError(`Table "log" has no primary key`))
+		})
+	})
+
 	Context("when the provided type is not a pointer", func() {
 		It("should panic", func() {
 			Expect(func() { sqlutil.NewEntityContext(student{}) }).To(Panic())
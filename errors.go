@@ -0,0 +1,245 @@
+package sqlutil
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned when the requested entity does not exist. It
+	// matches sql.ErrNoRows as well.
+	ErrNotFound = errors.New("Entity not found")
+	// ErrNoRowsAffected is returned by Update and Delete when no row matches
+	// the primary key of the entity. The MySQL connections must report the
+	// matched rows for Update, see MySQLDialect.
+	ErrNoRowsAffected = errors.New("No rows affected")
+	// ErrStaleEntity is returned by Update when the version of the entity does
+	// not match the version of the row, which was changed concurrently
+	ErrStaleEntity = errors.New("Entity is stale")
+
+	ErrUniqueViolation     = errors.New("Unique constraint violation")
+	ErrForeignKeyViolation = errors.New("Foreign key constraint violation")
+	ErrNotNullViolation    = errors.New("Not null constraint violation")
+	ErrCheckViolation      = errors.New("Check constraint violation")
+)
+
+// ConstraintError is returned when a statement violates a constraint. It
+// matches the violation error of its kind such as ErrUniqueViolation and
+// unwraps to the driver error.
+type ConstraintError struct {
+	Kind       error
+	Constraint string
+	Table      string
+	Columns    []string
+	Err        error
+}
+
+func (e *ConstraintError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Kind, e.Err)
+}
+
+func (e *ConstraintError) Is(target error) bool {
+	return e.Kind == target
+}
+
+func (e *ConstraintError) Unwrap() error {
+	return e.Err
+}
+
+type notFoundError struct {
+	err error
+}
+
+func (e *notFoundError) Error() string {
+	return ErrNotFound.Error()
+}
+
+func (e *notFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+func (e *notFoundError) Unwrap() error {
+	return e.err
+}
+
+func notFound(err error) error {
+	if err == sql.ErrNoRows {
+		return &notFoundError{err: err}
+	}
+	return err
+}
+
+var (
+	sqliteConstraintRegexp = regexp.MustCompile(`^(UNIQUE|NOT NULL|CHECK|FOREIGN KEY) constraint failed(?:: (.+))?$`)
+	mysqlDuplicateRegexp   = regexp.MustCompile("for key '(?:[^'.]+\\.)?([^']+)'")
+	mysqlForeignKeyRegexp  = regexp.MustCompile("CONSTRAINT `([^`]+)` FOREIGN KEY \\(`([^`]+)`\\)")
+	mysqlColumnRegexp      = regexp.MustCompile("Column '([^']+)'")
+	mysqlCheckRegexp       = regexp.MustCompile("Check constraint '([^']+)'")
+	sqlserverObjectRegexp  = regexp.MustCompile(`(?:constraint|index) '([^']+)'`)
+	sqlserverColumnRegexp  = regexp.MustCompile(`column '([^']+)'`)
+)
+
+func translateSQLiteError(err error) error {
+	matches := sqliteConstraintRegexp.FindStringSubmatch(err.Error())
+	if matches == nil {
+		return err
+	}
+
+	cerr := &ConstraintError{Err: err}
+
+	switch matches[1] {
+	case "UNIQUE":
+		cerr.Kind = ErrUniqueViolation
+	case "NOT NULL":
+		cerr.Kind = ErrNotNullViolation
+	case "CHECK":
+		cerr.Kind = ErrCheckViolation
+		cerr.Constraint = matches[2]
+		return cerr
+	default:
+		cerr.Kind = ErrForeignKeyViolation
+		return cerr
+	}
+
+	for _, name := range strings.Split(matches[2], ", ") {
+		parts := strings.SplitN(name, ".", 2)
+		if len(parts) == 2 {
+			cerr.Table = parts[0]
+			cerr.Columns = append(cerr.Columns, parts[1])
+		}
+	}
+
+	return cerr
+}
+
+func translatePostgresError(err error) error {
+	code, _ := driverField(err, "Code").(string)
+	kinds := map[string]error{
+		"23505": ErrUniqueViolation,
+		"23503": ErrForeignKeyViolation,
+		"23502": ErrNotNullViolation,
+		"23514": ErrCheckViolation,
+	}
+
+	kind, ok := kinds[code]
+	if !ok {
+		return err
+	}
+
+	cerr := &ConstraintError{Kind: kind, Err: err}
+	cerr.Constraint, _ = driverField(err, "Constraint", "ConstraintName").(string)
+	cerr.Table, _ = driverField(err, "Table", "TableName").(string)
+
+	if column, _ := driverField(err, "Column", "ColumnName").(string); column != "" {
+		cerr.Columns = []string{column}
+	}
+
+	return cerr
+}
+
+func translateMySQLError(err error) error {
+	var cerr *ConstraintError
+	message := err.Error()
+
+	switch driverNumber(err) {
+	case 1062:
+		cerr = &ConstraintError{Kind: ErrUniqueViolation, Err: err}
+		if matches := mysqlDuplicateRegexp.FindStringSubmatch(message); matches != nil {
+			cerr.Constraint = matches[1]
+		}
+	case 1451, 1452:
+		cerr = &ConstraintError{Kind: ErrForeignKeyViolation, Err: err}
+		if matches := mysqlForeignKeyRegexp.FindStringSubmatch(message); matches != nil {
+			cerr.Constraint = matches[1]
+			cerr.Columns = []string{matches[2]}
+		}
+	case 1048, 1364:
+		cerr = &ConstraintError{Kind: ErrNotNullViolation, Err: err}
+		if matches := mysqlColumnRegexp.FindStringSubmatch(message); matches != nil {
+			cerr.Columns = []string{matches[1]}
+		}
+	case 3819:
+		cerr = &ConstraintError{Kind: ErrCheckViolation, Err: err}
+		if matches := mysqlCheckRegexp.FindStringSubmatch(message); matches != nil {
+			cerr.Constraint = matches[1]
+		}
+	default:
+		return err
+	}
+
+	return cerr
+}
+
+func translateSQLServerError(err error) error {
+	var cerr *ConstraintError
+	message := err.Error()
+
+	switch driverNumber(err) {
+	case 2601, 2627:
+		cerr = &ConstraintError{Kind: ErrUniqueViolation, Err: err}
+	case 547:
+		if strings.Contains(message, "CHECK constraint") {
+			cerr = &ConstraintError{Kind: ErrCheckViolation, Err: err}
+		} else {
+			cerr = &ConstraintError{Kind: ErrForeignKeyViolation, Err: err}
+		}
+
+		if matches := sqlserverColumnRegexp.FindStringSubmatch(message); matches != nil {
+			cerr.Columns = []string{matches[1]}
+		}
+	case 515:
+		cerr = &ConstraintError{Kind: ErrNotNullViolation, Err: err}
+		if matches := sqlserverColumnRegexp.FindStringSubmatch(message); matches != nil {
+			cerr.Columns = []string{matches[1]}
+		}
+		return cerr
+	default:
+		return err
+	}
+
+	if matches := sqlserverObjectRegexp.FindStringSubmatch(message); matches != nil {
+		cerr.Constraint = matches[1]
+	}
+
+	return cerr
+}
+
+// driverField returns the value of the first field with one of the given
+// names of a driver error in the chain of err. The drivers are not imported
+// so their error types are inspected with reflection.
+func driverField(err error, names ...string) interface{} {
+	for ; err != nil; err = errors.Unwrap(err) {
+		v := reflect.Indirect(reflect.ValueOf(err))
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+
+		for _, name := range names {
+			if field := v.FieldByName(name); field.IsValid() && field.CanInterface() {
+				if field.Kind() == reflect.String {
+					return field.String()
+				}
+				return field.Interface()
+			}
+		}
+	}
+
+	return nil
+}
+
+func driverNumber(err error) int64 {
+	v := reflect.ValueOf(driverField(err, "Number"))
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint())
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,161 @@
+package sqlutil_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/phogolabs/sqlutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type pqErrorCode string
+
+type pqError struct {
+	Code       pqErrorCode
+	Message    string
+	Table      string
+	Column     string
+	Constraint string
+}
+
+func (e *pqError) Error() string {
+	return "pq: " + e.Message
+}
+
+type mysqlError struct {
+	Number  uint16
+	Message string
+}
+
+func (e *mysqlError) Error() string {
+	return fmt.Sprintf("Error %d: %s", e.Number, e.Message)
+}
+
+type mssqlError struct {
+	Number  int32
+	Message string
+}
+
+func (e mssqlError) Error() string {
+	return "mssql: " + e.Message
+}
+
+var _ = Describe("Errors", func() {
+	type student struct {
+		ID   string `sql:"id,varchar(50),pk"`
+		Name string `sql:"name,text,not_null"`
+	}
+
+	BeforeEach(func() {
+		Expect(sqlutil.CreateTable(db, &student{})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		_, err := db.Exec("drop table student")
+		Expect(err).To(BeNil())
+	})
+
+	It("returns not found error", func() {
+		err := sqlutil.QueryRow(db, &student{ID: "1"})
+		Expect(errors.Is(err, sqlutil.ErrNotFound)).To(BeTrue())
+		Expect(errors.Is(err, sql.ErrNoRows)).To(BeTrue())
+	})
+
+	It("returns no rows affected error", func() {
+		_, err := sqlutil.Update(db, &student{ID: "1", Name: "Jack"})
+		Expect(err).To(MatchError(sqlutil.ErrNoRowsAffected))
+
+		_, err = sqlutil.Delete(db, &student{ID: "1"})
+		Expect(err).To(MatchError(sqlutil.ErrNoRowsAffected))
+	})
+
+	It("returns unique constraint error", func() {
+		_, err := sqlutil.Insert(db, &student{ID: "1", Name: "Jack"})
+		Expect(err).To(BeNil())
+
+		_, err = sqlutil.Insert(db, &student{ID: "1", Name: "John"})
+		Expect(errors.Is(err, sqlutil.ErrUniqueViolation)).To(BeTrue())
+
+		cerr := &sqlutil.ConstraintError{}
+		Expect(errors.As(err, &cerr)).To(BeTrue())
+		Expect(cerr.Table).To(Equal("student"))
+		Expect(cerr.Columns).To(Equal([]string{"id"}))
+	})
+
+	It("returns not null constraint error", func() {
+		_, err := db.Exec("INSERT INTO student (id) VALUES ('1')")
+		err = sqlutil.SQLiteDialect.TranslateError(err)
+		Expect(errors.Is(err, sqlutil.ErrNotNullViolation)).To(BeTrue())
+
+		cerr := &sqlutil.ConstraintError{}
+		Expect(errors.As(err, &cerr)).To(BeTrue())
+		Expect(cerr.Columns).To(Equal([]string{"name"}))
+	})
+
+	It("translates postgres errors", func() {
+		err := sqlutil.PostgreSQLDialect.TranslateError(&pqError{
+			Code:       "23503",
+			Message:    "insert or update violates foreign key constraint",
+			Table:      "enrollment",
+			Column:     "student_id",
+			Constraint: "enrollment_student_fk",
+		})
+
+		Expect(errors.Is(err, sqlutil.ErrForeignKeyViolation)).To(BeTrue())
+
+		cerr := &sqlutil.ConstraintError{}
+		Expect(errors.As(err, &cerr)).To(BeTrue())
+		Expect(cerr.Constraint).To(Equal("enrollment_student_fk"))
+		Expect(cerr.Table).To(Equal("enrollment"))
+		Expect(cerr.Columns).To(Equal([]string{"student_id"}))
+
+		perr := &pqError{}
+		Expect(errors.As(err, &perr)).To(BeTrue())
+
+		other := &pqError{Code: "42P01", Message: "relation does not exist"}
+		Expect(sqlutil.PostgreSQLDialect.TranslateError(other)).To(BeIdenticalTo(other))
+	})
+
+	It("translates mysql errors", func() {
+		err := sqlutil.MySQLDialect.TranslateError(&mysqlError{Number: 1062, Message: "Duplicate entry 'jack' for key 'student.name_idx'"})
+		Expect(errors.Is(err, sqlutil.ErrUniqueViolation)).To(BeTrue())
+
+		cerr := &sqlutil.ConstraintError{}
+		Expect(errors.As(err, &cerr)).To(BeTrue())
+		Expect(cerr.Constraint).To(Equal("name_idx"))
+
+		err = sqlutil.MySQLDialect.TranslateError(&mysqlError{
+			Number:  1452,
+			Message: "Cannot add or update a child row: a foreign key constraint fails (`db`.`enrollment`, CONSTRAINT `enrollment_fk` FOREIGN KEY (`student_id`) REFERENCES `student` (`id`))",
+		})
+		Expect(errors.Is(err, sqlutil.ErrForeignKeyViolation)).To(BeTrue())
+		Expect(errors.As(err, &cerr)).To(BeTrue())
+		Expect(cerr.Constraint).To(Equal("enrollment_fk"))
+		Expect(cerr.Columns).To(Equal([]string{"student_id"}))
+
+		err = sqlutil.MySQLDialect.TranslateError(&mysqlError{Number: 1048, Message: "Column 'name' cannot be null"})
+		Expect(errors.Is(err, sqlutil.ErrNotNullViolation)).To(BeTrue())
+		Expect(errors.As(err, &cerr)).To(BeTrue())
+		Expect(cerr.Columns).To(Equal([]string{"name"}))
+	})
+
+	It("translates sql server errors", func() {
+		err := sqlutil.SQLServerDialect.TranslateError(mssqlError{
+			Number:  2627,
+			Message: "Violation of UNIQUE KEY constraint 'student_name'. Cannot insert duplicate key in object 'dbo.student'.",
+		})
+		Expect(errors.Is(err, sqlutil.ErrUniqueViolation)).To(BeTrue())
+
+		cerr := &sqlutil.ConstraintError{}
+		Expect(errors.As(err, &cerr)).To(BeTrue())
+		Expect(cerr.Constraint).To(Equal("student_name"))
+	})
+
+	It("wraps the errors translated from a wrapped driver error", func() {
+		err := sqlutil.PostgreSQLDialect.TranslateError(fmt.Errorf("insert: %w", &pqError{Code: "23505"}))
+		Expect(errors.Is(err, sqlutil.ErrUniqueViolation)).To(BeTrue())
+	})
+})
@@ -0,0 +1,20 @@
+package sqlutil
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Executor executes statements against a database. It is satisfied by
+// *sql.DB, *sql.Tx and *sql.Conn.
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+var (
+	_ Executor = &sql.DB{}
+	_ Executor = &sql.Tx{}
+	_ Executor = &sql.Conn{}
+)
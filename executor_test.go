@@ -0,0 +1,81 @@
+package sqlutil_test
+
+import (
+	"context"
+
+	"github.com/phogolabs/sqlutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Executor", func() {
+	type student struct {
+		ID   string `sql:"id,varchar(50),pk"`
+		Name string `sql:"name,text"`
+	}
+
+	BeforeEach(func() {
+		Expect(sqlutil.CreateTable(db, &student{})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		_, err := db.Exec("drop table student")
+		Expect(err).To(BeNil())
+	})
+
+	count := func() int {
+		cnt := 0
+		Expect(db.QueryRow("SELECT count(*) FROM student").Scan(&cnt)).To(Succeed())
+		return cnt
+	}
+
+	It("inserts and updates entities inside a committed transaction", func() {
+		tx, err := db.Begin()
+		Expect(err).To(BeNil())
+
+		s := &student{ID: "1", Name: "Jack"}
+		_, err = sqlutil.Insert(tx, s)
+		Expect(err).To(BeNil())
+
+		s.Name = "John"
+		_, err = sqlutil.Update(tx, s)
+		Expect(err).To(BeNil())
+
+		Expect(tx.Commit()).To(Succeed())
+
+		record := &student{ID: "1"}
+		Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+		Expect(record.Name).To(Equal("John"))
+	})
+
+	It("discards the entities of a rolled back transaction", func() {
+		tx, err := db.Begin()
+		Expect(err).To(BeNil())
+
+		_, err = sqlutil.Insert(tx, &student{ID: "1", Name: "Jack"})
+		Expect(err).To(BeNil())
+		_, err = sqlutil.Insert(tx, &student{ID: "2", Name: "John"})
+		Expect(err).To(BeNil())
+
+		Expect(tx.Rollback()).To(Succeed())
+		Expect(count()).To(Equal(0))
+	})
+
+	It("works with a dedicated connection", func() {
+		ctx := context.Background()
+
+		conn, err := db.Conn(ctx)
+		Expect(err).To(BeNil())
+		defer func() {
+			Expect(conn.Close()).To(Succeed())
+		}()
+
+		_, err = sqlutil.InsertContext(ctx, conn, &student{ID: "1", Name: "Jack"})
+		Expect(err).To(BeNil())
+
+		record := &student{ID: "1"}
+		Expect(sqlutil.QueryRowContext(ctx, conn, record)).To(Succeed())
+		Expect(record.Name).To(Equal("Jack"))
+	})
+})
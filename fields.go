@@ -0,0 +1,211 @@
+package sqlutil
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Expression is a SQL expression assigned to a column by Update instead of a
+// value
+type Expression struct {
+	SQL  string
+	Args []interface{}
+}
+
+// Expr returns an expression such as Expr("count + ?", 1) whose ? are
+// replaced by the bind parameters of the args
+func Expr(sql string, args ...interface{}) Expression {
+	return Expression{SQL: sql, Args: args}
+}
+
+// render returns the expression with bind parameters that start at position
+func (e Expression) render(d Dialect, position int) string {
+	parts := strings.Split(e.SQL, "?")
+	buffer := &strings.Builder{}
+
+	for index, part := range parts {
+		if index > 0 {
+			buffer.WriteString(d.Placeholder(position + index - 1))
+		}
+		buffer.WriteString(part)
+	}
+
+	return buffer.String()
+}
+
+// resolveFields merges the fields and returns them by column name with the
+// values converted to the types of the column fields. The keys may be column
+// names or Go field names.
+func (t *EntityContext) resolveFields(fields []Fields) (Fields, bool, error) {
+	allFields, merged := mergeFields(fields)
+	resolved := Fields{}
+
+	for key, value := range allFields {
+		column := t.fieldColumn(key)
+		if column == nil {
+			return nil, false, fmt.Errorf("Unknown column %q for table %q", key, t.schema.Table)
+		}
+
+		if _, ok := resolved[column.Name]; ok {
+			return nil, false, fmt.Errorf("Column %q is set more than once", column.Name)
+		}
+
+		converted, err := convertValue(column, value)
+		if err != nil {
+			return nil, false, err
+		}
+
+		resolved[column.Name] = converted
+	}
+
+	return resolved, merged, nil
+}
+
+// updatable returns an error when a resolved field belongs to a column that
+// is not written by Update
+func (t *EntityContext) updatable(fields Fields) error {
+	for name := range fields {
+		if column := t.schema.column(name); !column.updatable() {
+			return fmt.Errorf("Column %q of table %q cannot be updated", name, t.schema.Table)
+		}
+	}
+
+	return nil
+}
+
+// fieldColumn returns the column with the name or the Go field name key
+func (t *EntityContext) fieldColumn(key string) *Column {
+	if column := t.schema.column(key); column != nil {
+		return column
+	}
+
+	for _, column := range t.schema.Columns {
+		if fieldName(t.modelValue.Type(), column.Index) == key {
+			return column
+		}
+	}
+
+	return nil
+}
+
+// fieldName returns the Go name of the field with the index sequence. The
+// names of the nested fields are joined with dot and the embedded structs
+// are skipped.
+func fieldName(t reflect.Type, index []int) string {
+	names := []string{}
+
+	for _, position := range index {
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		field := t.Field(position)
+		if !field.Anonymous {
+			names = append(names, field.Name)
+		}
+
+		t = field.Type
+	}
+
+	return strings.Join(names, ".")
+}
+
+// assignments returns the assignment of the value or expression to the
+// column, whose placeholders start at position, and its bind values
+func (t *EntityContext) assignments(column string, value interface{}, position int) (string, []interface{}) {
+	if expr, ok := value.(Expression); ok {
+		return fmt.Sprintf("%s = %s", t.Dialect().Quote(column), expr.render(t.Dialect(), position)), expr.Args
+	}
+
+	return t.assignment(column, position), []interface{}{value}
+}
+
+// convertValue converts the value to the type of the column field or to its
+// element type when the field is a pointer
+func convertValue(column *Column, value interface{}) (interface{}, error) {
+	if expr, ok := value.(Expression); ok {
+		if count := strings.Count(expr.SQL, "?"); count != len(expr.Args) {
+			return nil, fmt.Errorf("Expression %q of column %q expects %d arguments; got %d", expr.SQL, column.Name, count, len(expr.Args))
+		}
+		return value, nil
+	}
+
+	if value == nil {
+		return nil, nil
+	}
+
+	source := reflect.ValueOf(value)
+	target := column.Type
+
+	if source.Type().AssignableTo(target) {
+		return value, nil
+	}
+
+	if target.Kind() == reflect.Ptr {
+		target = target.Elem()
+	}
+
+	if source.Type().AssignableTo(target) {
+		return value, nil
+	}
+
+	if converted, ok := convertType(source, target); ok {
+		return converted, nil
+	}
+
+	if scanner, ok := reflect.New(target).Interface().(sql.Scanner); ok {
+		if err := scanner.Scan(value); err == nil {
+			return reflect.ValueOf(scanner).Elem().Interface(), nil
+		}
+	}
+
+	return nil, fmt.Errorf("Cannot use %T as value of column %q of type %s", value, column.Name, column.Type)
+}
+
+// convertType converts the value to the target type when it is convertible
+// without loss. Numbers are not converted to strings and slices are converted
+// only to arrays of the same length.
+func convertType(source reflect.Value, target reflect.Type) (interface{}, bool) {
+	if !source.Type().ConvertibleTo(target) {
+		return nil, false
+	}
+
+	if source.Kind() == reflect.Slice {
+		array := target
+		if array.Kind() == reflect.Ptr {
+			array = array.Elem()
+		}
+
+		if array.Kind() == reflect.Array && array.Len() != source.Len() {
+			return nil, false
+		}
+	}
+
+	if !numeric(source.Kind()) {
+		return source.Convert(target).Interface(), true
+	}
+
+	if !numeric(target.Kind()) {
+		return nil, false
+	}
+
+	converted := source.Convert(target)
+	if converted.Convert(source.Type()).Interface() != source.Interface() {
+		return nil, false
+	}
+
+	return converted.Interface(), true
+}
+
+func numeric(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
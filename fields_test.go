@@ -0,0 +1,151 @@
+package sqlutil_test
+
+import (
+	"database/sql"
+
+	"github.com/phogolabs/sqlutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fields", func() {
+	type Address struct {
+		City string `sql:"city,text"`
+	}
+
+	type product struct {
+		ID      string         `sql:"id,varchar(50),pk"`
+		Name    string         `sql:"name,text"`
+		Count   int32          `sql:"count,integer"`
+		Price   *float64       `sql:"price,real"`
+		Note    sql.NullString `sql:"note,text"`
+		Address Address        `sqlprefix:"address_"`
+	}
+
+	var recordDB *sql.DB
+
+	BeforeEach(func() {
+		Expect(sqlutil.CreateTable(db, &product{})).To(Succeed())
+		_, err := sqlutil.Insert(db, &product{ID: "1", Name: "pen", Count: 5})
+		Expect(err).To(BeNil())
+
+		recordDB, err = sql.Open("sqlutil-recorder", "")
+		Expect(err).To(BeNil())
+		recorder.Reset()
+	})
+
+	AfterEach(func() {
+		Expect(recordDB.Close()).To(Succeed())
+		_, err := db.Exec("drop table product")
+		Expect(err).To(BeNil())
+	})
+
+	load := func() *product {
+		p := &product{ID: "1"}
+		Expect(sqlutil.QueryRow(db, p)).To(Succeed())
+		return p
+	}
+
+	It("accepts the column names and the Go field names", func() {
+		_, err := sqlutil.Update(db, &product{ID: "1"}, sqlutil.Fields{"Name": "pencil", "address_city": "Sofia"})
+		Expect(err).To(BeNil())
+
+		_, err = sqlutil.Update(db, &product{ID: "1"}, sqlutil.Fields{"Address.City": "Varna"})
+		Expect(err).To(BeNil())
+
+		p := load()
+		Expect(p.Name).To(Equal("pencil"))
+		Expect(p.Address.City).To(Equal("Varna"))
+	})
+
+	It("converts the values to the field types", func() {
+		_, err := sqlutil.Update(db, &product{ID: "1"}, sqlutil.Fields{"count": 7, "price": 2, "note": "sale"})
+		Expect(err).To(BeNil())
+
+		p := load()
+		Expect(p.Count).To(Equal(int32(7)))
+		Expect(*p.Price).To(Equal(float64(2)))
+		Expect(p.Note).To(Equal(sql.NullString{String: "sale", Valid: true}))
+
+		_, err = sqlutil.Update(db, &product{ID: "1"}, sqlutil.Fields{"price": nil})
+		Expect(err).To(BeNil())
+		Expect(load().Price).To(BeNil())
+	})
+
+	It("sets the SQL expressions", func() {
+		_, err := sqlutil.Update(db, &product{ID: "1"}, sqlutil.Fields{"count": sqlutil.Expr("count + ?", 3)})
+		Expect(err).To(BeNil())
+		Expect(load().Count).To(Equal(int32(8)))
+
+		_, err = sqlutil.UpdateWhere(db, &product{}, sqlutil.Where("id", sqlutil.Eq, "1"), sqlutil.Fields{"Count": sqlutil.Expr("count * 2")})
+		Expect(err).To(BeNil())
+		Expect(load().Count).To(Equal(int32(16)))
+	})
+
+	It("renders the expressions with the dialect placeholders", func() {
+		entity := sqlutil.NewEntityContext(&product{ID: "1"}).WithDialect(sqlutil.PostgreSQLDialect)
+		_, err := entity.Update(recordDB, sqlutil.Fields{"name": "pen", "count": sqlutil.Expr("count + ?", 1)})
+		Expect(err).To(BeNil())
+		Expect(recorder.statements).To(Equal([]string{
+			`UPDATE "product" SET "name" = $1,"count" = count + $2 WHERE "id" = $3`,
+		}))
+	})
+
+	Context("when the fields are invalid", func() {
+		It("returns an error for an unknown column", func() {
+			_, err := sqlutil.Update(db, &product{ID: "1"}, sqlutil.Fields{"nmae": "pencil"})
+			Expect(err).To(MatchError(`Unknown column "nmae" for table "product"`))
+
+			_, err = sqlutil.Upsert(db, &product{ID: "1"}, sqlutil.Fields{"nmae": "pencil"})
+			Expect(err).To(MatchError(`Unknown column "nmae" for table "product"`))
+
+			_, err = sqlutil.UpdateWhere(db, &product{}, sqlutil.Where("id", sqlutil.Eq, "1"), sqlutil.Fields{"nmae": "pencil"})
+			Expect(err).To(MatchError(`Unknown column "nmae" for table "product"`))
+
+			Expect(load().Name).To(Equal("pen"))
+		})
+
+		It("returns an error for a value of wrong type", func() {
+			_, err := sqlutil.Update(db, &product{ID: "1"}, sqlutil.Fields{"name": 5})
+			Expect(err).To(MatchError(`Cannot use int as value of column "name" of type string`))
+
+			_, err = sqlutil.Update(db, &product{ID: "1"}, sqlutil.Fields{"count": int64(1) << 40})
+			Expect(err).To(MatchError(`Cannot use int64 as value of column "count" of type int32`))
+		})
+
+		It("returns an error for a slice that does not fit the array", func() {
+			type token struct {
+				ID  string   `sql:"id,varchar(50),pk"`
+				Key [16]byte `sql:"key,blob"`
+			}
+
+			_, err := sqlutil.Update(recordDB, &token{ID: "1"}, sqlutil.Fields{"key": []byte{1, 2}})
+			Expect(err).To(MatchError(`Cannot use []uint8 as value of column "key" of type [16]uint8`))
+		})
+
+		It("returns an error when a column is set twice", func() {
+			_, err := sqlutil.Update(db, &product{ID: "1"}, sqlutil.Fields{"name": "a", "Name": "b"})
+			Expect(err).To(MatchError(`Column "name" is set more than once`))
+		})
+
+		It("returns an error for a column that cannot be updated", func() {
+			_, err := sqlutil.Update(db, &product{ID: "1"}, sqlutil.Fields{"id": "2"})
+			Expect(err).To(MatchError(`Column "id" of table "product" cannot be updated`))
+
+			_, err = sqlutil.UpdateWhere(db, &product{}, sqlutil.Where("id", sqlutil.Eq, "1"), sqlutil.Fields{"ID": "2"})
+			Expect(err).To(MatchError(`Column "id" of table "product" cannot be updated`))
+			Expect(load().Name).To(Equal("pen"))
+		})
+
+		It("returns an error when the expression arguments do not match", func() {
+			_, err := sqlutil.Update(db, &product{ID: "1"}, sqlutil.Fields{"count": sqlutil.Expr("count + ?")})
+			Expect(err).To(MatchError(`Expression "count + ?" of column "count" expects 1 arguments; got 0`))
+		})
+
+		It("returns an error for an expression in upsert", func() {
+			_, err := sqlutil.Upsert(db, &product{ID: "1"}, sqlutil.Fields{"count": sqlutil.Expr("count + 1")})
+			Expect(err).To(MatchError(`Expression of column "count" is not supported by Upsert`))
+		})
+	})
+})
@@ -0,0 +1,95 @@
+package sqlutil
+
+import "context"
+
+// BeforeInserter is implemented by models that are prepared or validated
+// before Insert. An error aborts the operation.
+type BeforeInserter interface {
+	BeforeInsert(ctx context.Context, db Executor) error
+}
+
+// AfterInserter is implemented by models that are notified after Insert
+type AfterInserter interface {
+	AfterInsert(ctx context.Context, db Executor) error
+}
+
+// BeforeUpdater is implemented by models that are prepared or validated
+// before Update. An error aborts the operation.
+type BeforeUpdater interface {
+	BeforeUpdate(ctx context.Context, db Executor) error
+}
+
+// AfterUpdater is implemented by models that are notified after Update
+type AfterUpdater interface {
+	AfterUpdate(ctx context.Context, db Executor) error
+}
+
+// BeforeDeleter is implemented by models that are checked before Delete. An
+// error aborts the operation.
+type BeforeDeleter interface {
+	BeforeDelete(ctx context.Context, db Executor) error
+}
+
+// AfterDeleter is implemented by models that are notified after Delete
+type AfterDeleter interface {
+	AfterDelete(ctx context.Context, db Executor) error
+}
+
+// AfterScanner is implemented by models that are completed after a row is
+// scanned into them. The executor is nil when the row is scanned by Scan.
+type AfterScanner interface {
+	AfterScan(ctx context.Context, db Executor) error
+}
+
+func (t *EntityContext) model() interface{} {
+	return t.modelValue.Addr().Interface()
+}
+
+func (t *EntityContext) beforeInsert(ctx context.Context, db Executor) error {
+	if hook, ok := t.model().(BeforeInserter); ok {
+		return hook.BeforeInsert(ctx, db)
+	}
+	return nil
+}
+
+func (t *EntityContext) afterInsert(ctx context.Context, db Executor) error {
+	if hook, ok := t.model().(AfterInserter); ok {
+		return hook.AfterInsert(ctx, db)
+	}
+	return nil
+}
+
+func (t *EntityContext) beforeUpdate(ctx context.Context, db Executor) error {
+	if hook, ok := t.model().(BeforeUpdater); ok {
+		return hook.BeforeUpdate(ctx, db)
+	}
+	return nil
+}
+
+func (t *EntityContext) afterUpdate(ctx context.Context, db Executor) error {
+	if hook, ok := t.model().(AfterUpdater); ok {
+		return hook.AfterUpdate(ctx, db)
+	}
+	return nil
+}
+
+func (t *EntityContext) beforeDelete(ctx context.Context, db Executor) error {
+	if hook, ok := t.model().(BeforeDeleter); ok {
+		return hook.BeforeDelete(ctx, db)
+	}
+	return nil
+}
+
+func (t *EntityContext) afterDelete(ctx context.Context, db Executor) error {
+	if hook, ok := t.model().(AfterDeleter); ok {
+		return hook.AfterDelete(ctx, db)
+	}
+	return nil
+}
+
+func (t *EntityContext) afterScan(ctx context.Context, db Executor) error {
+	if hook, ok := t.model().(AfterScanner); ok {
+		return hook.AfterScan(ctx, db)
+	}
+	return nil
+}
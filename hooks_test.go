@@ -0,0 +1,183 @@
+package sqlutil_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/phogolabs/sqlutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type member struct {
+	ID     string   `sql:"id,varchar(50),pk"`
+	Email  string   `sql:"email,text"`
+	Events []string `sql:"-"`
+	Fail   string   `sql:"-"`
+}
+
+func (m *member) record(ctx context.Context, db sqlutil.Executor, event string) error {
+	if ctx == nil {
+		return fmt.Errorf("Missing context")
+	}
+
+	if db != nil {
+		event += " with executor"
+	}
+
+	m.Events = append(m.Events, event)
+
+	if m.Fail == event {
+		return fmt.Errorf("Hook %s failed", event)
+	}
+
+	return nil
+}
+
+func (m *member) BeforeInsert(ctx context.Context, db sqlutil.Executor) error {
+	m.Email = strings.ToLower(m.Email)
+	return m.record(ctx, db, "before insert")
+}
+
+func (m *member) AfterInsert(ctx context.Context, db sqlutil.Executor) error {
+	return m.record(ctx, db, "after insert")
+}
+
+func (m *member) BeforeUpdate(ctx context.Context, db sqlutil.Executor) error {
+	return m.record(ctx, db, "before update")
+}
+
+func (m *member) AfterUpdate(ctx context.Context, db sqlutil.Executor) error {
+	return m.record(ctx, db, "after update")
+}
+
+func (m *member) BeforeDelete(ctx context.Context, db sqlutil.Executor) error {
+	return m.record(ctx, db, "before delete")
+}
+
+func (m *member) AfterDelete(ctx context.Context, db sqlutil.Executor) error {
+	return m.record(ctx, db, "after delete")
+}
+
+func (m *member) AfterScan(ctx context.Context, db sqlutil.Executor) error {
+	return m.record(ctx, db, "after scan")
+}
+
+var _ = Describe("Hooks", func() {
+	BeforeEach(func() {
+		Expect(sqlutil.CreateTable(db, &member{})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		_, err := db.Exec("drop table member")
+		Expect(err).To(BeNil())
+	})
+
+	count := func() int64 {
+		var cnt int64
+		Expect(db.QueryRow("SELECT count(*) FROM member").Scan(&cnt)).To(Succeed())
+		return cnt
+	}
+
+	It("calls the hooks around the operations", func() {
+		m := &member{ID: "1", Email: "Jack@Example.com"}
+
+		_, err := sqlutil.Insert(db, m)
+		Expect(err).To(BeNil())
+		_, err = sqlutil.Update(db, m)
+		Expect(err).To(BeNil())
+		_, err = sqlutil.Delete(db, m)
+		Expect(err).To(BeNil())
+
+		Expect(m.Email).To(Equal("jack@example.com"))
+		Expect(m.Events).To(Equal([]string{
+			"before insert with executor",
+			"after insert with executor",
+			"before update with executor",
+			"after update with executor",
+			"before delete with executor",
+			"after delete with executor",
+		}))
+	})
+
+	It("calls the after scan hook", func() {
+		_, err := sqlutil.Insert(db, &member{ID: "1"})
+		Expect(err).To(BeNil())
+
+		m := &member{ID: "1"}
+		Expect(sqlutil.QueryRow(db, m)).To(Succeed())
+		Expect(m.Events).To(Equal([]string{"after scan with executor"}))
+
+		members := []member{}
+		Expect(sqlutil.Select(db, &members, "SELECT * FROM member")).To(Succeed())
+		Expect(members).To(HaveLen(1))
+		Expect(members[0].Events).To(Equal([]string{"after scan with executor"}))
+
+		cursor, err := sqlutil.QueryCursor[member](db, "SELECT * FROM member")
+		Expect(err).To(BeNil())
+		Expect(cursor.Each(func(item *member) error {
+			Expect(item.Events).To(Equal([]string{"after scan with executor"}))
+			return nil
+		})).To(Succeed())
+
+		rows, err := db.Query("SELECT * FROM member")
+		Expect(err).To(BeNil())
+		defer rows.Close()
+		Expect(rows.Next()).To(BeTrue())
+
+		m = &member{}
+		Expect(sqlutil.Scan(rows, m)).To(Succeed())
+		Expect(m.Events).To(Equal([]string{"after scan"}))
+	})
+
+	It("calls the insert hooks of every model of a batch", func() {
+		members := []*member{{ID: "1", Email: "A"}, {ID: "2", Email: "B"}}
+
+		_, err := sqlutil.InsertAll(db, members)
+		Expect(err).To(BeNil())
+
+		for _, m := range members {
+			Expect(m.Events).To(Equal([]string{"before insert with executor", "after insert with executor"}))
+		}
+
+		Expect(members[1].Email).To(Equal("b"))
+	})
+
+	Context("when a before hook fails", func() {
+		It("aborts the operation", func() {
+			_, err := sqlutil.Insert(db, &member{ID: "1", Fail: "before insert with executor"})
+			Expect(err).To(MatchError("Hook before insert with executor failed"))
+			Expect(count()).To(BeZero())
+
+			_, err = sqlutil.InsertAll(db, []*member{{ID: "1"}, {ID: "2", Fail: "before insert with executor"}})
+			Expect(err).To(HaveOccurred())
+			Expect(count()).To(BeZero())
+
+			_, err = sqlutil.Insert(db, &member{ID: "1"})
+			Expect(err).To(BeNil())
+
+			m := &member{ID: "1", Email: "changed", Fail: "before update with executor"}
+			_, err = sqlutil.Update(db, m)
+			Expect(err).To(HaveOccurred())
+
+			m.Fail = "before delete with executor"
+			_, err = sqlutil.Delete(db, m)
+			Expect(err).To(HaveOccurred())
+			Expect(count()).To(Equal(int64(1)))
+
+			record := &member{ID: "1"}
+			Expect(sqlutil.QueryRow(db, record)).To(Succeed())
+			Expect(record.Email).To(BeEmpty())
+		})
+	})
+
+	Context("when an after hook fails", func() {
+		It("returns the error of the hook", func() {
+			cnt, err := sqlutil.Insert(db, &member{ID: "1", Fail: "after insert with executor"})
+			Expect(err).To(MatchError("Hook after insert with executor failed"))
+			Expect(cnt).To(Equal(int64(1)))
+		})
+	})
+})
@@ -1,109 +1,401 @@
 package sqlutil
 
 import (
+	"database/sql"
 	"fmt"
 	"reflect"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 )
 
 var (
-	metadata         *Metadata
+	metadata               = &Metadata{}
 	ignoredFieldErr  error = fmt.Errorf("Field is ignored")
-	foreignKeyRegexp       = regexp.MustCompile(`([\w]+)\(([\w]+)\)`)
+	foreignKeyRegexp       = regexp.MustCompile(`^(\w+(?:\.\w+)*)\((\w+)\)$`)
 )
 
-func init() {
-	metadata = &Metadata{}
-}
-
 const (
 	TagColumnName         = "sql"
 	TagIndexName          = "sqlindex"
 	TagForeignKeyName     = "sqlforeignkey"
+	TagTableName          = "sqltable"
+	TagPrefixName         = "sqlprefix"
 	TagFieldNameIndex     = 0
 	TagFieldDataTypeIndex = 1
 )
 
+// MetadataOptions configures a Metadata registry. Empty fields fall back to
+// the package defaults.
+type MetadataOptions struct {
+	Dialect Dialect
+	// Clock provides the time of the timestamps, which are normalized to UTC
+	Clock Clock
+	// Naming derives the table names and the column names of the exported
+	// fields without column tag, which are rejected when it is not set
+	Naming        NamingStrategy
+	ColumnTag     string
+	IndexTag      string
+	ForeignKeyTag string
+	TableTag      string
+	PrefixTag     string
+}
+
+// Metadata is a registry of the schemas of the model types. It is safe for
+// concurrent use. The zero value uses the default options.
 type Metadata struct {
-	info map[reflect.Type]*Schema
+	mu      sync.RWMutex
+	options MetadataOptions
+	info    map[reflect.Type]*Schema
+	// generation is incremented when the cached schemas are dropped
+	generation int
 }
 
-func (m *Metadata) Schema(t reflect.Type) (*Schema, error) {
-	if m.info == nil {
-		m.info = map[reflect.Type]*Schema{}
+func NewMetadata(options MetadataOptions) *Metadata {
+	return &Metadata{options: options}
+}
+
+// DefaultMetadata returns the registry used by the package level functions
+func DefaultMetadata() *Metadata {
+	return metadata
+}
+
+func (m *Metadata) Dialect() Dialect {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.options.Dialect == nil {
+		return SQLiteDialect
+	}
+	return m.options.Dialect
+}
+
+func (m *Metadata) SetDialect(d Dialect) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.options.Dialect = d
+}
+
+// SetNaming sets the naming strategy and drops the cached schemas, whose names
+// may have been derived by the previous strategy
+func (m *Metadata) SetNaming(n NamingStrategy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.options.Naming = n
+	m.info = nil
+	m.generation++
+}
+
+func (m *Metadata) SetClock(c Clock) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.options.Clock = c
+}
+
+// now returns the current time of the clock in UTC
+func (m *Metadata) now() time.Time {
+	m.mu.RLock()
+	clock := m.options.Clock
+	m.mu.RUnlock()
+
+	if clock == nil {
+		return time.Now().UTC()
+	}
+	return clock.Now().UTC()
+}
+
+func (m *Metadata) NewEntityContext(model interface{}) *EntityContext {
+	entity, err := m.entity(model)
+	if err != nil {
+		panic(err)
+	}
+
+	return entity
+}
+
+func (m *Metadata) entity(model interface{}) (*EntityContext, error) {
+	typ, err := typeOf(model)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := m.Schema(typ)
+	if err != nil {
+		return nil, err
 	}
 
+	return &EntityContext{
+		metadata:   m,
+		modelValue: valueOf(model),
+		schema:     schema,
+	}, nil
+}
+
+func (m *Metadata) Schema(t reflect.Type) (*Schema, error) {
+	m.mu.RLock()
 	schema, ok := m.info[t]
+	naming := m.options.Naming
+	generation := m.generation
+	m.mu.RUnlock()
+
 	if ok {
 		return schema, nil
 	}
 
-	schema = &Schema{
-		Table:       strings.ToLower(t.Name()),
+	schema, err := m.schema(t, naming)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if cached, ok := m.info[t]; ok {
+		return cached, nil
+	}
+
+	// the schema built with the strategy replaced meanwhile is not cached
+	if generation != m.generation {
+		return schema, nil
+	}
+
+	if m.info == nil {
+		m.info = map[reflect.Type]*Schema{}
+	}
+
+	m.info[t] = schema
+	return schema, nil
+}
+
+// TableNamer is implemented by models whose table name differs from the one
+// derived by the naming strategy. The name may be schema-qualified.
+type TableNamer interface {
+	TableName() string
+}
+
+// schema builds the schema of t. The names are derived by the naming
+// strategy, which is nil when the column tags are required.
+func (m *Metadata) schema(t reflect.Type, naming NamingStrategy) (*Schema, error) {
+	schema := &Schema{
+		Table:       m.table(t, naming),
 		ForeignKeys: []*ForeignKey{},
 		Columns:     []*Column{},
 		Indexes:     []*Index{},
 	}
 
-	m.info[t] = schema
+	if err := m.fields(schema, t, naming, []int{}, ""); err != nil {
+		return nil, fmt.Errorf("Type %q: %v", t.Name(), err)
+	}
 
+	return schema, nil
+}
+
+// fields adds the columns of the struct fields of t, whose field index path
+// starts with path and whose names start with prefix
+func (m *Metadata) fields(schema *Schema, t reflect.Type, naming NamingStrategy, path []int, prefix string) error {
 	for index := 0; index < t.NumField(); index++ {
 		field := t.Field(index)
+		fieldPath := append(append([]int{}, path...), index)
+
+		if nested, nestedPrefix, ok := m.nested(field); ok {
+			if err := m.fields(schema, nested, naming, fieldPath, prefix+nestedPrefix); err != nil {
+				return err
+			}
+			continue
+		}
 
 		if field.PkgPath != "" {
 			continue
 		}
 
 		column := &Column{
-			Index: index,
+			Index: fieldPath,
+			Type:  field.Type,
 		}
 
-		if err := m.column(column, field); err != nil {
+		if err := m.column(column, field, naming); err != nil {
 			if err == ignoredFieldErr {
 				continue
 			}
-			return nil, fmt.Errorf("Type %q: %v", t.Name(), err)
+			return err
+		}
+
+		column.Name = prefix + column.Name
+
+		// the columns with the conventional names are audit timestamps when
+		// their type supports it
+		if timestampType(column.Type) {
+			column.Created = column.Created || column.Name == FieldCreatedAt
+			column.Updated = column.Updated || column.Name == FieldUpdatedAt
+		}
+
+		if column.Name == FieldDeletedAt && !nullableTimestampType(column.Type) {
+			return fmt.Errorf("Soft delete field %q must be *time.Time, sql.NullTime or *int64; got %s", field.Name, field.Type)
 		}
 
 		m.index(schema, column, field)
-		m.foreignKey(schema, column, field)
+		if err := m.foreignKey(schema, column, field); err != nil {
+			return err
+		}
 
 		schema.Columns = append(schema.Columns, column)
 	}
 
-	return schema, nil
+	return nil
+}
+
+// nested returns the struct type of a field whose fields are flattened into
+// the schema and the prefix of their column names. Embedded structs without
+// column tag are flattened without prefix and the other struct fields only
+// when they have a prefix tag.
+func (m *Metadata) nested(field reflect.StructField) (reflect.Type, string, bool) {
+	t := field.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil, "", false
+	}
+
+	if field.PkgPath != "" && (!field.Anonymous || field.Type.Kind() == reflect.Ptr) {
+		return nil, "", false
+	}
+
+	if prefixes, ok := Tag(field.Tag).Lookup(m.tag(m.options.PrefixTag, TagPrefixName)); ok && len(prefixes) > 0 {
+		return t, prefixes[0], true
+	}
+
+	if field.Anonymous && field.Tag.Get(m.tag(m.options.ColumnTag, TagColumnName)) == "" {
+		return t, "", true
+	}
+
+	return nil, "", false
 }
 
-func (m *Metadata) column(column *Column, field reflect.StructField) error {
-	columnTag := field.Tag.Get(TagColumnName)
+// table returns the name returned by the TableNamer interface, set by the
+// table tag of a blank field or derived by the naming strategy
+func (m *Metadata) table(t reflect.Type, naming NamingStrategy) string {
+	if namer, ok := reflect.New(t).Interface().(TableNamer); ok {
+		return namer.TableName()
+	}
+
+	tagName := m.tag(m.options.TableTag, TagTableName)
+
+	for index := 0; index < t.NumField(); index++ {
+		field := t.Field(index)
+
+		if field.Name != "_" {
+			continue
+		}
+
+		if name := field.Tag.Get(tagName); name != "" {
+			return name
+		}
+	}
+
+	return namingOrDefault(naming).Table(t.Name())
+}
+
+func namingOrDefault(naming NamingStrategy) NamingStrategy {
+	if naming == nil {
+		return LowerCaseNaming
+	}
+	return naming
+}
+
+func (m *Metadata) tag(name, defaultName string) string {
+	if name == "" {
+		return defaultName
+	}
+	return name
+}
+
+func (m *Metadata) column(column *Column, field reflect.StructField, naming NamingStrategy) error {
+	columnTag := field.Tag.Get(m.tag(m.options.ColumnTag, TagColumnName))
 
 	if columnTag == "-" {
 		return ignoredFieldErr
 	}
 
-	if columnTag == "" {
+	if columnTag == "" && naming == nil {
 		return fmt.Errorf("Missing tag for field %q", field.Name)
 	}
 
 	for index, meta := range strings.Split(columnTag, ",") {
 		if meta == "pk" {
 			column.PrimaryKey = true
-		} else {
-			switch index {
-			case TagFieldNameIndex:
-				column.Name = meta
-			case TagFieldDataTypeIndex:
-				column.DataType = meta
-			default:
-				column.Constraint |= m.constraints(meta)
-			}
+			continue
+		}
+
+		// the options follow the name and the data type, which may be
+		// named like the options
+		switch index {
+		case TagFieldNameIndex:
+			column.Name = meta
+		case TagFieldDataTypeIndex:
+			column.DataType = meta
+		default:
+			m.option(column, meta)
 		}
 	}
 
+	if column.Name == "" {
+		column.Name = namingOrDefault(naming).Column(field.Name)
+	}
+
+	if (column.Created || column.Updated) && !timestampType(field.Type) {
+		return fmt.Errorf("Audit timestamp field %q must be time.Time, *time.Time, sql.NullTime or int64; got %s", field.Name, field.Type)
+	}
+
 	return nil
 }
 
+// option sets the column option or constraint of the tag value meta
+func (m *Metadata) option(column *Column, meta string) {
+	switch meta {
+	case "auto", "autoincrement":
+		column.AutoIncrement = true
+	case "generated", "readonly":
+		column.Generated = true
+	case "version":
+		column.Version = true
+	case "created":
+		column.Created = true
+	case "updated":
+		column.Updated = true
+	case "millis":
+		column.Millis = true
+	case "omitempty":
+		column.OmitEmpty = true
+	default:
+		column.Constraint |= m.constraints(meta)
+	}
+}
+
+// timestampType reports whether the audit timestamps can be set to a field
+// of type t. The int64 fields store Unix time.
+func timestampType(t reflect.Type) bool {
+	switch t {
+	case reflect.TypeOf(time.Time{}), reflect.TypeOf(&time.Time{}), reflect.TypeOf(sql.NullTime{}):
+		return true
+	default:
+		return t.Kind() == reflect.Int64
+	}
+}
+
+// nullableTimestampType reports whether the soft delete timestamp can be set
+// to and cleared from a field of type t
+func nullableTimestampType(t reflect.Type) bool {
+	switch t {
+	case reflect.TypeOf(&time.Time{}), reflect.TypeOf(sql.NullTime{}):
+		return true
+	default:
+		return t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Int64
+	}
+}
+
 func (m *Metadata) constraints(meta string) ColumnConstraint {
 	switch meta {
 	case "unique":
@@ -117,15 +409,15 @@ func (m *Metadata) constraints(meta string) ColumnConstraint {
 	}
 }
 
-func (m *Metadata) foreignKey(schema *Schema, column *Column, field reflect.StructField) {
+func (m *Metadata) foreignKey(schema *Schema, column *Column, field reflect.StructField) error {
 	tag := Tag(field.Tag)
 
-	for _, fkTag := range tag.Get(TagForeignKeyName) {
+	for _, fkTag := range tag.Get(m.tag(m.options.ForeignKeyTag, TagForeignKeyName)) {
 		found := false
 
 		matches := foreignKeyRegexp.FindStringSubmatch(fkTag)
 		if len(matches) < 3 {
-			continue
+			return fmt.Errorf("Foreign key %q of field %q must be table(column)", fkTag, field.Name)
 		}
 
 		referenceTable := matches[1]
@@ -148,17 +440,22 @@ func (m *Metadata) foreignKey(schema *Schema, column *Column, field reflect.Stru
 			})
 		}
 	}
+
+	return nil
 }
 
 func (m *Metadata) index(schema *Schema, column *Column, field reflect.StructField) {
 	tag := Tag(field.Tag)
 
-	for _, indexTag := range tag.Get(TagIndexName) {
+	for _, indexTag := range tag.Get(m.tag(m.options.IndexTag, TagIndexName)) {
 		found := false
+		options := strings.Split(indexTag, ",")
+		name := options[0]
 
 		for _, index := range schema.Indexes {
-			if index.Name == indexTag {
+			if index.Name == name {
 				index.Columns = append(index.Columns, column.Name)
+				index.Unique = index.Unique || contains(options[1:], "unique")
 				found = true
 				break
 			}
@@ -166,7 +463,8 @@ func (m *Metadata) index(schema *Schema, column *Column, field reflect.StructFie
 
 		if !found {
 			schema.Indexes = append(schema.Indexes, &Index{
-				Name:    indexTag,
+				Name:    name,
+				Unique:  contains(options[1:], "unique"),
 				Columns: []string{column.Name},
 			})
 		}
@@ -1,6 +1,9 @@
 package sqlutil
 
-import "strings"
+import (
+	"reflect"
+	"strings"
+)
 
 const (
 	ColumnConstraintUnique ColumnConstraint = 1 << iota
@@ -15,6 +18,15 @@ type Schema struct {
 	Indexes     []*Index
 }
 
+func (s *Schema) column(name string) *Column {
+	for _, column := range s.Columns {
+		if column.Name == name {
+			return column
+		}
+	}
+	return nil
+}
+
 type ForeignKey struct {
 	Columns               []string
 	ReferenceTable        string
@@ -22,18 +34,45 @@ type ForeignKey struct {
 }
 
 type Column struct {
-	Name       string
-	Index      int
+	Name string
+	// Index is the index sequence of the field for reflect.Value.FieldByIndex
+	Index      []int
+	Type       reflect.Type
 	DataType   string
 	PrimaryKey bool
+	// AutoIncrement marks a key generated by the database, which is omitted
+	// from INSERT and written back into the field
+	AutoIncrement bool
+	// Generated marks a column filled by the database, which is omitted from
+	// INSERT and UPDATE and read back into the field
+	Generated bool
+	// Version marks the column used for optimistic locking, which is checked
+	// and incremented by Update
+	Version bool
+	// Created and Updated mark the audit timestamps set on insert and on
+	// insert and update respectively
+	Created bool
+	Updated bool
+	// Millis stores the integer timestamps in milliseconds since Unix epoch
+	// instead of seconds
+	Millis bool
+	// OmitEmpty omits the column from INSERT when the field has zero value so
+	// the DEFAULT expression of the column applies
+	OmitEmpty  bool
 	Constraint ColumnConstraint
 }
 
 type Index struct {
 	Name    string
+	Unique  bool
 	Columns []string
 }
 
+// updatable reports whether the column is set by Update from the field
+func (c *Column) updatable() bool {
+	return !c.PrimaryKey && !c.AutoIncrement && !c.Generated && !c.Version
+}
+
 type ColumnConstraint byte
 
 func (c ColumnConstraint) String() string {
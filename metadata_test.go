@@ -1,7 +1,9 @@
 package sqlutil_test
 
 import (
+	"database/sql"
 	"reflect"
+	"sync"
 	"time"
 
 	"github.com/phogolabs/sqlutil"
@@ -10,6 +12,14 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+type invoice struct {
+	ID string `sql:"id,varchar(50),pk"`
+}
+
+func (invoice) TableName() string {
+	return "billing.invoices"
+}
+
 var _ = Describe("Metadata", func() {
 	var metadata *sqlutil.Metadata
 
@@ -93,6 +103,301 @@ var _ = Describe("Metadata", func() {
 
 	})
 
+	It("retrieves the unique indexes", func() {
+		type m struct {
+			ID    string `sql:"id,varchar(50),pk"`
+			Email string `sql:"email,text" sqlindex:"email_idx,unique" sqlindex:"search"`
+		}
+
+		t := reflect.ValueOf(m{}).Type()
+		schema, err := metadata.Schema(t)
+		Expect(err).To(BeNil())
+
+		indexes := schema.Indexes
+		Expect(indexes).To(HaveLen(2))
+		Expect(indexes[0].Name).To(Equal("email_idx"))
+		Expect(indexes[0].Unique).To(BeTrue())
+		Expect(indexes[0].Columns).To(Equal([]string{"email"}))
+		Expect(indexes[1].Name).To(Equal("search"))
+		Expect(indexes[1].Unique).To(BeFalse())
+	})
+
+	It("caches the schema safely for concurrent use", func() {
+		type m struct {
+			ID string `sql:"id,varchar(50),pk"`
+		}
+
+		t := reflect.ValueOf(m{}).Type()
+		schemas := make([]*sqlutil.Schema, 16)
+		group := sync.WaitGroup{}
+
+		for index := range schemas {
+			group.Add(1)
+
+			go func(index int) {
+				defer GinkgoRecover()
+				defer group.Done()
+
+				schema, err := metadata.Schema(t)
+				Expect(err).To(BeNil())
+				schemas[index] = schema
+			}(index)
+		}
+
+		group.Wait()
+
+		for _, schema := range schemas {
+			Expect(schema).To(BeIdenticalTo(schemas[0]))
+		}
+	})
+
+	Context("when the registry is created with options", func() {
+		type m struct {
+			ID   string `db:"id,varchar(50),pk"`
+			Name string `db:"name,text" idx:"m_name" fk:"n(name)"`
+		}
+
+		BeforeEach(func() {
+			metadata = sqlutil.NewMetadata(sqlutil.MetadataOptions{
+				Dialect:       sqlutil.PostgreSQLDialect,
+				ColumnTag:     "db",
+				IndexTag:      "idx",
+				ForeignKeyTag: "fk",
+			})
+		})
+
+		It("uses the configured tag names", func() {
+			schema, err := metadata.Schema(reflect.TypeOf(m{}))
+			Expect(err).To(BeNil())
+			Expect(schema.Columns).To(HaveLen(2))
+			Expect(schema.Columns[1].Name).To(Equal("name"))
+			Expect(schema.Indexes).To(HaveLen(1))
+			Expect(schema.Indexes[0].Name).To(Equal("m_name"))
+			Expect(schema.ForeignKeys).To(HaveLen(1))
+			Expect(schema.ForeignKeys[0].ReferenceTable).To(Equal("n"))
+		})
+
+		It("creates entities that use the configured dialect", func() {
+			recordDB, err := sql.Open("sqlutil-recorder", "")
+			Expect(err).To(BeNil())
+			defer recordDB.Close()
+			recorder.Reset()
+
+			entity := metadata.NewEntityContext(&m{ID: "1"})
+			Expect(entity.Dialect()).To(Equal(sqlutil.PostgreSQLDialect))
+
+			_, err = entity.Delete(recordDB)
+			Expect(err).To(BeNil())
+			Expect(recorder.statements).To(Equal([]string{`DELETE FROM "m" WHERE "id" = $1`}))
+		})
+
+		It("does not share the schemas with the default registry", func() {
+			Expect(sqlutil.DefaultMetadata()).NotTo(BeIdenticalTo(metadata))
+			Expect(sqlutil.DefaultMetadata().Dialect()).To(Equal(sqlutil.SQLiteDialect))
+
+			_, err := sqlutil.DefaultMetadata().Schema(reflect.TypeOf(m{}))
+			Expect(err).To(MatchError(`Type "m": Missing tag for field "ID"`))
+		})
+	})
+
+	Context("when the type has nested structs", func() {
+		type audit struct {
+			CreatedBy string `sql:"created_by,text"`
+		}
+
+		type Timestamps struct {
+			CreatedAt time.Time `sql:"created_at,timestamp"`
+			UpdatedAt time.Time `sql:"updated_at,timestamp"`
+		}
+
+		type Address struct {
+			Street string `sql:"street,text" sqlindex:"street_idx"`
+			City   string `sql:"city,text"`
+		}
+
+		type m struct {
+			ID string `sql:"id,varchar(50),pk"`
+			*Timestamps
+			audit
+			Home     Address   `sqlprefix:"home_"`
+			Work     *Address  `sqlprefix:"work_"`
+			Born     time.Time `sql:"born,timestamp"`
+			Internal Address   `sql:"-"`
+		}
+
+		It("flattens the embedded and prefixed structs", func() {
+			schema, err := metadata.Schema(reflect.TypeOf(m{}))
+			Expect(err).To(BeNil())
+
+			names := []string{}
+			indexes := [][]int{}
+
+			for _, column := range schema.Columns {
+				names = append(names, column.Name)
+				indexes = append(indexes, column.Index)
+			}
+
+			Expect(names).To(Equal([]string{
+				"id", "created_at", "updated_at", "created_by",
+				"home_street", "home_city", "work_street", "work_city", "born",
+			}))
+
+			Expect(indexes).To(Equal([][]int{
+				{0}, {1, 0}, {1, 1}, {2, 0},
+				{3, 0}, {3, 1}, {4, 0}, {4, 1}, {5},
+			}))
+
+			Expect(schema.Indexes).To(HaveLen(1))
+			Expect(schema.Indexes[0].Columns).To(Equal([]string{"home_street", "work_street"}))
+		})
+	})
+
+	It("retrieves the foreign keys of schema-qualified tables", func() {
+		type m struct {
+			ID        string `sql:"id,varchar(50),pk"`
+			InvoiceID string `sql:"invoice_id,varchar(50)" sqlforeignkey:"billing.invoices(id)"`
+		}
+
+		schema, err := metadata.Schema(reflect.TypeOf(m{}))
+		Expect(err).To(BeNil())
+		Expect(schema.ForeignKeys).To(HaveLen(1))
+		Expect(schema.ForeignKeys[0].ReferenceTable).To(Equal("billing.invoices"))
+		Expect(schema.ForeignKeys[0].ReferenceTableColumns).To(Equal([]string{"id"}))
+		Expect(schema.ForeignKeys[0].Columns).To(Equal([]string{"invoice_id"}))
+	})
+
+	It("returns an error when a foreign key is malformed", func() {
+		type m struct {
+			ID      string `sql:"id,varchar(50),pk"`
+			OrderID string `sql:"order_id,varchar(50)" sqlforeignkey:"orders(id) on delete"`
+		}
+
+		_, err := metadata.Schema(reflect.TypeOf(m{}))
+		Expect(err).To(MatchError(`Type "m": Foreign key "orders(id) on delete" of field "OrderID" must be table(column)`))
+	})
+
+	It("retrieves the auto-increment option", func() {
+		type m struct {
+			ID    int64 `sql:"id,bigint,pk,auto"`
+			RefID int64 `sql:"ref_id,bigint,autoincrement"`
+			Count int64 `sql:"count,bigint"`
+		}
+
+		schema, err := metadata.Schema(reflect.TypeOf(m{}))
+		Expect(err).To(BeNil())
+		Expect(schema.Columns[0].AutoIncrement).To(BeTrue())
+		Expect(schema.Columns[0].PrimaryKey).To(BeTrue())
+		Expect(schema.Columns[1].AutoIncrement).To(BeTrue())
+		Expect(schema.Columns[2].AutoIncrement).To(BeFalse())
+	})
+
+	It("does not treat the column names as options", func() {
+		type m struct {
+			ID      string `sql:"id,varchar(50),pk"`
+			Version int    `sql:"version,integer"`
+			Created string `sql:"created,text"`
+			Auto    bool   `sql:"auto,boolean"`
+		}
+
+		schema, err := metadata.Schema(reflect.TypeOf(m{}))
+		Expect(err).To(BeNil())
+
+		names := []string{}
+		for _, column := range schema.Columns[1:] {
+			names = append(names, column.Name)
+			Expect(column.Version || column.Created || column.AutoIncrement).To(BeFalse(), column.Name)
+		}
+
+		Expect(names).To(Equal([]string{"version", "created", "auto"}))
+	})
+
+	It("retrieves the generated option", func() {
+		type m struct {
+			ID       string `sql:"id,varchar(50),pk"`
+			Slug     string `sql:"slug,text,generated"`
+			Revision int    `sql:"revision,integer,readonly"`
+		}
+
+		schema, err := metadata.Schema(reflect.TypeOf(m{}))
+		Expect(err).To(BeNil())
+		Expect(schema.Columns[0].Generated).To(BeFalse())
+		Expect(schema.Columns[1].Generated).To(BeTrue())
+		Expect(schema.Columns[2].Generated).To(BeTrue())
+	})
+
+	It("retrieves the audit timestamp options", func() {
+		type m struct {
+			ID         string       `sql:"id,varchar(50),pk"`
+			CreatedAt  time.Time    `sql:"created_at,timestamp"`
+			UpdatedAt  string       `sql:"updated_at,text"`
+			InsertedOn int64        `sql:"inserted_on,bigint,created,millis"`
+			ModifiedOn sql.NullTime `sql:"modified_on,timestamp,updated"`
+		}
+
+		schema, err := metadata.Schema(reflect.TypeOf(m{}))
+		Expect(err).To(BeNil())
+		Expect(schema.Columns[1].Created).To(BeTrue())
+		Expect(schema.Columns[2].Updated).To(BeFalse())
+		Expect(schema.Columns[3].Created).To(BeTrue())
+		Expect(schema.Columns[3].Millis).To(BeTrue())
+		Expect(schema.Columns[4].Updated).To(BeTrue())
+		Expect(schema.Columns[4].Created).To(BeFalse())
+	})
+
+	It("returns an error when an audit timestamp has unsupported type", func() {
+		type m struct {
+			ID         string `sql:"id,varchar(50),pk"`
+			ModifiedOn string `sql:"modified_on,text,updated"`
+		}
+
+		_, err := metadata.Schema(reflect.TypeOf(m{}))
+		Expect(err).To(MatchError(`Type "m": Audit timestamp field "ModifiedOn" must be time.Time, *time.Time, sql.NullTime or int64; got string`))
+	})
+
+	It("returns an error when the soft delete timestamp is not nullable", func() {
+		type m struct {
+			ID        string    `sql:"id,varchar(50),pk"`
+			DeletedAt time.Time `sql:"deleted_at,timestamp"`
+		}
+
+		_, err := metadata.Schema(reflect.TypeOf(m{}))
+		Expect(err).To(MatchError(`Type "m": Soft delete field "DeletedAt" must be *time.Time, sql.NullTime or *int64; got time.Time`))
+	})
+
+	It("retrieves the omitempty option", func() {
+		type m struct {
+			ID     string `sql:"id,varchar(50),pk"`
+			Status string `sql:"status,text,omitempty,not_null"`
+		}
+
+		schema, err := metadata.Schema(reflect.TypeOf(m{}))
+		Expect(err).To(BeNil())
+		Expect(schema.Columns[0].OmitEmpty).To(BeFalse())
+		Expect(schema.Columns[1].OmitEmpty).To(BeTrue())
+		Expect(schema.Columns[1].DataType).To(Equal("text"))
+		Expect(schema.Columns[1].Constraint).To(Equal(sqlutil.ColumnConstraintNotNull))
+	})
+
+	Context("when the table name is overridden", func() {
+		It("uses the name set by the table tag", func() {
+			type userAccount struct {
+				_  struct{} `sqltable:"user_accounts"`
+				ID string   `sql:"id,varchar(50),pk"`
+			}
+
+			schema, err := metadata.Schema(reflect.TypeOf(userAccount{}))
+			Expect(err).To(BeNil())
+			Expect(schema.Table).To(Equal("user_accounts"))
+			Expect(schema.Columns).To(HaveLen(1))
+		})
+
+		It("uses the name returned by TableName", func() {
+			schema, err := metadata.Schema(reflect.TypeOf(invoice{}))
+			Expect(err).To(BeNil())
+			Expect(schema.Table).To(Equal("billing.invoices"))
+		})
+	})
+
 	Context("when a tag is not provided", func() {
 		It("returns an error", func() {
 			type m struct {
@@ -0,0 +1,136 @@
+package sqlutil
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NamingStrategy derives the database names from the Go names
+type NamingStrategy interface {
+	// Table returns the table name of a type
+	Table(typeName string) string
+	// Column returns the column name of a field without sql tag
+	Column(fieldName string) string
+}
+
+var (
+	LowerCaseNaming NamingStrategy = &lowerCaseNaming{}
+	SnakeCaseNaming NamingStrategy = &snakeCaseNaming{}
+	CamelCaseNaming NamingStrategy = &camelCaseNaming{}
+)
+
+// SetNaming sets the naming strategy of the default registry
+func SetNaming(n NamingStrategy) {
+	metadata.SetNaming(n)
+}
+
+// PluralNaming returns a strategy that pluralizes the table names derived by
+// the given strategy
+func PluralNaming(strategy NamingStrategy) NamingStrategy {
+	return &pluralNaming{strategy: strategy}
+}
+
+type lowerCaseNaming struct{}
+
+func (n *lowerCaseNaming) Table(typeName string) string {
+	return strings.ToLower(typeName)
+}
+
+func (n *lowerCaseNaming) Column(fieldName string) string {
+	return strings.ToLower(fieldName)
+}
+
+type snakeCaseNaming struct{}
+
+func (n *snakeCaseNaming) Table(typeName string) string {
+	return strings.Join(words(typeName), "_")
+}
+
+func (n *snakeCaseNaming) Column(fieldName string) string {
+	return strings.Join(words(fieldName), "_")
+}
+
+type camelCaseNaming struct{}
+
+func (n *camelCaseNaming) Table(typeName string) string {
+	return camelCase(typeName)
+}
+
+func (n *camelCaseNaming) Column(fieldName string) string {
+	return camelCase(fieldName)
+}
+
+type pluralNaming struct {
+	strategy NamingStrategy
+}
+
+func (n *pluralNaming) Table(typeName string) string {
+	return plural(n.strategy.Table(typeName))
+}
+
+func (n *pluralNaming) Column(fieldName string) string {
+	return n.strategy.Column(fieldName)
+}
+
+// words splits a Go name into lower case words keeping acronyms together,
+// for instance HTTPServerID becomes http, server and id
+func words(name string) []string {
+	result := []string{}
+	runes := []rune(name)
+	start := 0
+
+	for index := 1; index <= len(runes); index++ {
+		if index < len(runes) && !boundary(runes, index) {
+			continue
+		}
+
+		if word := strings.Trim(string(runes[start:index]), "_"); word != "" {
+			result = append(result, strings.ToLower(word))
+		}
+
+		start = index
+	}
+
+	return result
+}
+
+func boundary(runes []rune, index int) bool {
+	current, previous := runes[index], runes[index-1]
+
+	switch {
+	case current == '_':
+		return true
+	case unicode.IsUpper(current) && unicode.IsLower(previous):
+		return true
+	case unicode.IsDigit(current) != unicode.IsDigit(previous) && unicode.IsUpper(current):
+		return true
+	case unicode.IsUpper(current) && unicode.IsUpper(previous):
+		return index+1 < len(runes) && unicode.IsLower(runes[index+1])
+	default:
+		return false
+	}
+}
+
+func camelCase(name string) string {
+	parts := words(name)
+
+	for index := 1; index < len(parts); index++ {
+		parts[index] = strings.ToUpper(parts[index][:1]) + parts[index][1:]
+	}
+
+	return strings.Join(parts, "")
+}
+
+func plural(name string) string {
+	lower := strings.ToLower(name)
+
+	switch {
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return name + "es"
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !strings.ContainsAny(lower[len(lower)-2:len(lower)-1], "aeiou"):
+		return name[:len(name)-1] + "ies"
+	default:
+		return name + "s"
+	}
+}
@@ -0,0 +1,198 @@
+package sqlutil_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/phogolabs/sqlutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Naming", func() {
+	It("derives lower case names", func() {
+		Expect(sqlutil.LowerCaseNaming.Table("UserAccount")).To(Equal("useraccount"))
+		Expect(sqlutil.LowerCaseNaming.Column("CreatedAt")).To(Equal("createdat"))
+	})
+
+	It("derives snake case names", func() {
+		Expect(sqlutil.SnakeCaseNaming.Table("UserAccount")).To(Equal("user_account"))
+		Expect(sqlutil.SnakeCaseNaming.Column("ID")).To(Equal("id"))
+		Expect(sqlutil.SnakeCaseNaming.Column("UserID")).To(Equal("user_id"))
+		Expect(sqlutil.SnakeCaseNaming.Column("HTTPServer")).To(Equal("http_server"))
+		Expect(sqlutil.SnakeCaseNaming.Column("Address2Line")).To(Equal("address2_line"))
+		Expect(sqlutil.SnakeCaseNaming.Column("Already_Snake")).To(Equal("already_snake"))
+	})
+
+	It("derives camel case names", func() {
+		Expect(sqlutil.CamelCaseNaming.Table("UserAccount")).To(Equal("userAccount"))
+		Expect(sqlutil.CamelCaseNaming.Column("ID")).To(Equal("id"))
+		Expect(sqlutil.CamelCaseNaming.Column("HTTPServerID")).To(Equal("httpServerId"))
+	})
+
+	It("derives plural table names", func() {
+		naming := sqlutil.PluralNaming(sqlutil.SnakeCaseNaming)
+		Expect(naming.Table("UserAccount")).To(Equal("user_accounts"))
+		Expect(naming.Table("Category")).To(Equal("categories"))
+		Expect(naming.Table("Day")).To(Equal("days"))
+		Expect(naming.Table("Box")).To(Equal("boxes"))
+		Expect(naming.Table("Address")).To(Equal("addresses"))
+		Expect(naming.Table("Branch")).To(Equal("branches"))
+		Expect(naming.Column("FirstName")).To(Equal("first_name"))
+	})
+
+	Context("when the registry has a naming strategy", func() {
+		type UserAccount struct {
+			ID        string `sql:",varchar(50),pk"`
+			FirstName string `sql:"given_name,text"`
+			LastName  string
+			CreatedAt time.Time
+			Ignored   string `sql:"-"`
+			secret    string
+		}
+
+		It("derives the names of the table and untagged columns", func() {
+			metadata := sqlutil.NewMetadata(sqlutil.MetadataOptions{
+				Naming: sqlutil.PluralNaming(sqlutil.SnakeCaseNaming),
+			})
+
+			schema, err := metadata.Schema(reflect.TypeOf(UserAccount{}))
+			Expect(err).To(BeNil())
+			Expect(schema.Table).To(Equal("user_accounts"))
+
+			names := []string{}
+			for _, column := range schema.Columns {
+				names = append(names, column.Name)
+			}
+
+			Expect(names).To(Equal([]string{"id", "given_name", "last_name", "created_at"}))
+			Expect(schema.Columns[0].PrimaryKey).To(BeTrue())
+			Expect(schema.Columns[0].DataType).To(Equal("varchar(50)"))
+		})
+
+		It("drops the cached schemas when the strategy changes", func() {
+			metadata := sqlutil.NewMetadata(sqlutil.MetadataOptions{
+				Naming: sqlutil.SnakeCaseNaming,
+			})
+
+			schema, err := metadata.Schema(reflect.TypeOf(UserAccount{}))
+			Expect(err).To(BeNil())
+			Expect(schema.Table).To(Equal("user_account"))
+
+			metadata.SetNaming(sqlutil.PluralNaming(sqlutil.SnakeCaseNaming))
+
+			schema, err = metadata.Schema(reflect.TypeOf(UserAccount{}))
+			Expect(err).To(BeNil())
+			Expect(schema.Table).To(Equal("user_accounts"))
+		})
+
+		It("changes the strategy safely for concurrent use", func() {
+			metadata := sqlutil.NewMetadata(sqlutil.MetadataOptions{
+				Naming: sqlutil.SnakeCaseNaming,
+			})
+
+			group := sync.WaitGroup{}
+
+			for index := 0; index < 16; index++ {
+				group.Add(2)
+
+				go func() {
+					defer GinkgoRecover()
+					defer group.Done()
+
+					_, err := metadata.Schema(reflect.TypeOf(UserAccount{}))
+					Expect(err).To(BeNil())
+				}()
+
+				go func() {
+					defer group.Done()
+					metadata.SetNaming(sqlutil.SnakeCaseNaming)
+				}()
+			}
+
+			group.Wait()
+			metadata.SetNaming(sqlutil.PluralNaming(sqlutil.SnakeCaseNaming))
+
+			schema, err := metadata.Schema(reflect.TypeOf(UserAccount{}))
+			Expect(err).To(BeNil())
+			Expect(schema.Table).To(Equal("user_accounts"))
+		})
+
+		It("sets the strategy of the default registry", func() {
+			sqlutil.SetNaming(sqlutil.SnakeCaseNaming)
+			defer sqlutil.SetNaming(nil)
+
+			schema, err := sqlutil.DefaultMetadata().Schema(reflect.TypeOf(UserAccount{}))
+			Expect(err).To(BeNil())
+			Expect(schema.Columns[2].Name).To(Equal("last_name"))
+		})
+
+		Context("when the registry is used for querying", func() {
+			type Author struct {
+				ID       string `sql:",varchar(50),pk"`
+				FullName string `sql:",text"`
+			}
+
+			var metadata *sqlutil.Metadata
+
+			BeforeEach(func() {
+				metadata = sqlutil.NewMetadata(sqlutil.MetadataOptions{
+					Naming: sqlutil.SnakeCaseNaming,
+				})
+
+				Expect(metadata.NewEntityContext(&Author{}).CreateTable(db)).To(Succeed())
+
+				affected, err := metadata.InsertAll(db, []Author{
+					{ID: "1", FullName: "Jack London"},
+					{ID: "2", FullName: "Mark Twain"},
+				})
+				Expect(err).To(BeNil())
+				Expect(affected).To(Equal(int64(2)))
+			})
+
+			AfterEach(func() {
+				_, err := db.Exec("drop table author")
+				Expect(err).To(BeNil())
+			})
+
+			It("selects the rows", func() {
+				authors := []Author{}
+				Expect(metadata.Select(db, &authors, "SELECT * FROM author ORDER BY id")).To(Succeed())
+				Expect(authors).To(HaveLen(2))
+				Expect(authors[1].FullName).To(Equal("Mark Twain"))
+			})
+
+			It("selects, updates and deletes the rows that match the query", func() {
+				q := sqlutil.Where("full_name", sqlutil.Eq, "Jack London")
+
+				affected, err := metadata.UpdateWhere(db, &Author{}, q, sqlutil.Fields{"full_name": "John London"})
+				Expect(err).To(BeNil())
+				Expect(affected).To(Equal(int64(1)))
+
+				authors := []Author{}
+				Expect(metadata.SelectWhere(db, &authors, sqlutil.Where("id", sqlutil.Eq, "1"))).To(Succeed())
+				Expect(authors).To(HaveLen(1))
+				Expect(authors[0].FullName).To(Equal("John London"))
+
+				affected, err = metadata.DeleteWhere(db, &Author{}, sqlutil.Where("id", sqlutil.Eq, "2"))
+				Expect(err).To(BeNil())
+				Expect(affected).To(Equal(int64(1)))
+			})
+
+			It("iterates the rows with a cursor", func() {
+				cursor, err := sqlutil.QueryMetadataCursorContext[Author](context.Background(), metadata, db, "SELECT * FROM author ORDER BY id")
+				Expect(err).To(BeNil())
+
+				names := []string{}
+				Expect(cursor.Each(func(a *Author) error {
+					names = append(names, a.FullName)
+					return nil
+				})).To(Succeed())
+				Expect(names).To(Equal([]string{"Jack London", "Mark Twain"}))
+			})
+		})
+	})
+})
@@ -0,0 +1,464 @@
+package sqlutil
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type Operator string
+
+const (
+	Eq        Operator = "="
+	NotEq     Operator = "<>"
+	Lt        Operator = "<"
+	Lte       Operator = "<="
+	Gt        Operator = ">"
+	Gte       Operator = ">="
+	Like      Operator = "LIKE"
+	NotLike   Operator = "NOT LIKE"
+	In        Operator = "IN"
+	NotIn     Operator = "NOT IN"
+	IsNull    Operator = "IS NULL"
+	IsNotNull Operator = "IS NOT NULL"
+)
+
+// Query is a set of conditions, ordering and pagination whose column names
+// are validated against the schema of the entity when rendered. The
+// soft-deleted rows are excluded unless IncludeDeleted is set.
+type Query struct {
+	clauses []*clause
+	orders  []*order
+	limit   int
+	offset  int
+	deleted bool
+}
+
+type order struct {
+	column     string
+	descending bool
+}
+
+type clause struct {
+	connector string
+	column    string
+	operator  Operator
+	values    []interface{}
+	group     *Query
+}
+
+func Where(column string, operator Operator, values ...interface{}) *Query {
+	return (&Query{}).And(column, operator, values...)
+}
+
+func (q *Query) And(column string, operator Operator, values ...interface{}) *Query {
+	return q.add(&clause{connector: "AND", column: column, operator: operator, values: values})
+}
+
+func (q *Query) Or(column string, operator Operator, values ...interface{}) *Query {
+	return q.add(&clause{connector: "OR", column: column, operator: operator, values: values})
+}
+
+// AndWhere adds the conditions of the group in parentheses joined with AND
+func (q *Query) AndWhere(group *Query) *Query {
+	return q.add(&clause{connector: "AND", group: group})
+}
+
+// OrWhere adds the conditions of the group in parentheses joined with OR
+func (q *Query) OrWhere(group *Query) *Query {
+	return q.add(&clause{connector: "OR", group: group})
+}
+
+// OrderBy orders the result by the column in ascending order. The column
+// must be a column of the schema.
+func (q *Query) OrderBy(column string) *Query {
+	q.orders = append(q.orders, &order{column: column})
+	return q
+}
+
+// OrderByDesc orders the result by the column in descending order
+func (q *Query) OrderByDesc(column string) *Query {
+	q.orders = append(q.orders, &order{column: column, descending: true})
+	return q
+}
+
+func (q *Query) Limit(limit int) *Query {
+	q.limit = limit
+	return q
+}
+
+func (q *Query) Offset(offset int) *Query {
+	q.offset = offset
+	return q
+}
+
+// IncludeDeleted makes the query match the soft-deleted rows as well
+func (q *Query) IncludeDeleted() *Query {
+	q.deleted = true
+	return q
+}
+
+func (q *Query) add(c *clause) *Query {
+	q.clauses = append(q.clauses, c)
+	return q
+}
+
+// where renders the conditions, whose placeholders start at position
+func (q *Query) where(d Dialect, schema *Schema, position int) (string, []interface{}, error) {
+	conditions := []string{}
+	values := make([]interface{}, 0)
+
+	for _, c := range q.clauses {
+		var (
+			condition string
+			args      []interface{}
+			err       error
+		)
+
+		if c.group != nil {
+			if condition, args, err = c.group.where(d, schema, position+len(values)); err != nil {
+				return "", nil, err
+			}
+
+			if condition == "" {
+				continue
+			}
+
+			condition = fmt.Sprintf("(%s)", condition)
+		} else if condition, args, err = c.render(d, schema, position+len(values)); err != nil {
+			return "", nil, err
+		}
+
+		if len(conditions) > 0 {
+			conditions = append(conditions, c.connector)
+		}
+
+		conditions = append(conditions, condition)
+		values = append(values, args...)
+	}
+
+	return strings.Join(conditions, " "), values, nil
+}
+
+// condition renders the conditions extended to exclude the soft-deleted rows
+func (q *Query) condition(d Dialect, schema *Schema, position int) (string, []interface{}, error) {
+	where, values, err := q.where(d, schema, position)
+	if err != nil || q.deleted || schema.column(FieldDeletedAt) == nil {
+		return where, values, err
+	}
+
+	if len(q.clauses) > 1 {
+		where = fmt.Sprintf("(%s)", where)
+	}
+
+	return notDeleted(d, schema, where), values, nil
+}
+
+func (c *clause) render(d Dialect, schema *Schema, position int) (string, []interface{}, error) {
+	if schema.column(c.column) == nil {
+		return "", nil, fmt.Errorf("Unknown column %q for table %q", c.column, schema.Table)
+	}
+
+	column := d.Quote(c.column)
+
+	switch c.operator {
+	case IsNull, IsNotNull:
+		if len(c.values) != 0 {
+			return "", nil, fmt.Errorf("Operator %q does not accept values", c.operator)
+		}
+
+		return fmt.Sprintf("%s %s", column, c.operator), nil, nil
+	case In, NotIn:
+		values := expand(c.values)
+		if len(values) == 0 {
+			return "", nil, fmt.Errorf("Operator %q expects at least one value", c.operator)
+		}
+
+		return fmt.Sprintf("%s %s %s", column, c.operator, placeholders(d, position, len(values))), values, nil
+	default:
+		if len(c.values) != 1 {
+			return "", nil, fmt.Errorf("Operator %q expects exactly one value", c.operator)
+		}
+
+		return fmt.Sprintf("%s %s %s", column, c.operator, d.Placeholder(position)), c.values, nil
+	}
+}
+
+// sql renders the WHERE, ORDER BY and pagination clauses of a SELECT
+func (q *Query) sql(d Dialect, schema *Schema) (string, []interface{}, error) {
+	if q == nil {
+		q = &Query{}
+	}
+
+	buffer := []string{}
+
+	where, values, err := q.condition(d, schema, 1)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if where != "" {
+		buffer = append(buffer, "WHERE "+where)
+	}
+
+	orders := []string{}
+	for _, order := range q.orders {
+		if schema.column(order.column) == nil {
+			return "", nil, fmt.Errorf("Unknown column %q for table %q", order.column, schema.Table)
+		}
+
+		item := d.Quote(order.column)
+		if order.descending {
+			item += " DESC"
+		}
+
+		orders = append(orders, item)
+	}
+
+	if len(orders) > 0 {
+		buffer = append(buffer, "ORDER BY "+strings.Join(orders, ","))
+	}
+
+	if limit := d.Limit(q.limit, q.offset, len(orders) > 0); limit != "" {
+		buffer = append(buffer, limit)
+	}
+
+	return strings.Join(buffer, " "), values, nil
+}
+
+func (q *Query) paginated() bool {
+	return q != nil && (len(q.orders) > 0 || q.limit > 0 || q.offset > 0)
+}
+
+func expand(values []interface{}) []interface{} {
+	if len(values) != 1 {
+		return values
+	}
+
+	v := reflect.ValueOf(values[0])
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		return values
+	}
+
+	expanded := make([]interface{}, v.Len())
+	for index := range expanded {
+		expanded[index] = v.Index(index).Interface()
+	}
+
+	return expanded
+}
+
+func SelectWhere(db Executor, dest interface{}, q *Query) error {
+	return metadata.SelectWhereContext(context.Background(), db, dest, q)
+}
+
+// SelectWhereContext selects the rows of the table of the slice element type
+// that match the query into dest. A nil query selects all rows.
+func SelectWhereContext(ctx context.Context, db Executor, dest interface{}, q *Query) error {
+	return metadata.SelectWhereContext(ctx, db, dest, q)
+}
+
+func (m *Metadata) SelectWhere(db Executor, dest interface{}, q *Query) error {
+	return m.SelectWhereContext(context.Background(), db, dest, q)
+}
+
+// SelectWhereContext selects the rows that match the query into dest using
+// the schemas and the dialect of the registry
+func (m *Metadata) SelectWhereContext(ctx context.Context, db Executor, dest interface{}, q *Query) error {
+	slice, err := sliceOf(dest)
+	if err != nil {
+		return err
+	}
+
+	structType := slice.Type().Elem()
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	schema, err := m.Schema(structType)
+	if err != nil {
+		return err
+	}
+
+	columns := []string{}
+	for _, column := range schema.Columns {
+		columns = append(columns, column.Name)
+	}
+
+	d := m.Dialect()
+	statement := fmt.Sprintf("SELECT %s FROM %s", strings.Join(quoteAll(d, columns), ","), d.Quote(schema.Table))
+
+	clause, values, err := q.sql(d, schema)
+	if err != nil {
+		return err
+	}
+
+	if clause != "" {
+		statement += " " + clause
+	}
+
+	return m.SelectContext(ctx, db, dest, statement, values...)
+}
+
+func UpdateWhere(db Executor, model interface{}, q *Query, fields Fields) (int64, error) {
+	return metadata.UpdateWhereContext(context.Background(), db, model, q, fields)
+}
+
+// UpdateWhereContext sets the fields of all rows of the model table that
+// match the query. The model is used only to determine the table.
+func UpdateWhereContext(ctx context.Context, db Executor, model interface{}, q *Query, fields Fields) (int64, error) {
+	return metadata.UpdateWhereContext(ctx, db, model, q, fields)
+}
+
+func (m *Metadata) UpdateWhere(db Executor, model interface{}, q *Query, fields Fields) (int64, error) {
+	return m.UpdateWhereContext(context.Background(), db, model, q, fields)
+}
+
+// UpdateWhereContext sets the fields of all rows of the model table that
+// match the query using the schemas and the dialect of the registry
+func (m *Metadata) UpdateWhereContext(ctx context.Context, db Executor, model interface{}, q *Query, fields Fields) (int64, error) {
+	entity, err := m.queryEntity(model, q)
+	if err != nil {
+		return 0, err
+	}
+
+	d := entity.Dialect()
+	columns := []string{}
+	values := make([]interface{}, 0)
+	now := entity.metadata.now()
+
+	fields, _, err = entity.resolveFields([]Fields{fields})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := entity.updatable(fields); err != nil {
+		return 0, err
+	}
+
+	for _, column := range entity.schema.Columns {
+		value, ok := fields[column.Name]
+		if !ok && column.Updated {
+			if value, err = timeValue(column, now); err != nil {
+				return 0, err
+			}
+
+			ok = true
+		}
+
+		if !ok {
+			continue
+		}
+
+		assignment, args := entity.assignments(column.Name, value, len(values)+1)
+		columns = append(columns, assignment)
+		values = append(values, args...)
+	}
+
+	if len(columns) == 0 {
+		return 0, fmt.Errorf("No fields to update")
+	}
+
+	statement := fmt.Sprintf("UPDATE %s SET %s", d.Quote(entity.schema.Table), strings.Join(columns, ","))
+
+	where, args, err := q.condition(d, entity.schema, len(values)+1)
+	if err != nil {
+		return 0, err
+	}
+
+	if where != "" {
+		statement += " WHERE " + where
+	}
+
+	return execSQL(ctx, db, d, statement, append(values, args...)...)
+}
+
+func DeleteWhere(db Executor, model interface{}, q *Query) (int64, error) {
+	return metadata.DeleteWhereContext(context.Background(), db, model, q)
+}
+
+// DeleteWhereContext deletes all rows of the model table that match the
+// query. The model is used only to determine the table. When the table has a
+// deleted_at column the rows are soft-deleted.
+func DeleteWhereContext(ctx context.Context, db Executor, model interface{}, q *Query) (int64, error) {
+	return metadata.DeleteWhereContext(ctx, db, model, q)
+}
+
+func (m *Metadata) DeleteWhere(db Executor, model interface{}, q *Query) (int64, error) {
+	return m.DeleteWhereContext(context.Background(), db, model, q)
+}
+
+// DeleteWhereContext deletes all rows of the model table that match the
+// query using the schemas and the dialect of the registry
+func (m *Metadata) DeleteWhereContext(ctx context.Context, db Executor, model interface{}, q *Query) (int64, error) {
+	entity, err := m.queryEntity(model, q)
+	if err != nil {
+		return 0, err
+	}
+
+	if entity.schema.column(FieldDeletedAt) == nil {
+		return deleteWhere(ctx, db, entity, q)
+	}
+
+	value, err := timeValue(entity.schema.column(FieldDeletedAt), m.now())
+	if err != nil {
+		return 0, err
+	}
+
+	return m.UpdateWhereContext(ctx, db, model, q, Fields{FieldDeletedAt: value})
+}
+
+func HardDeleteWhere(db Executor, model interface{}, q *Query) (int64, error) {
+	return metadata.HardDeleteWhereContext(context.Background(), db, model, q)
+}
+
+// HardDeleteWhereContext deletes all rows of the model table that match the
+// query even when the table supports soft delete
+func HardDeleteWhereContext(ctx context.Context, db Executor, model interface{}, q *Query) (int64, error) {
+	return metadata.HardDeleteWhereContext(ctx, db, model, q)
+}
+
+func (m *Metadata) HardDeleteWhere(db Executor, model interface{}, q *Query) (int64, error) {
+	return m.HardDeleteWhereContext(context.Background(), db, model, q)
+}
+
+// HardDeleteWhereContext deletes all rows of the model table that match the
+// query using the schemas and the dialect of the registry even when the
+// table supports soft delete
+func (m *Metadata) HardDeleteWhereContext(ctx context.Context, db Executor, model interface{}, q *Query) (int64, error) {
+	entity, err := m.queryEntity(model, q)
+	if err != nil {
+		return 0, err
+	}
+
+	return deleteWhere(ctx, db, entity, q)
+}
+
+func deleteWhere(ctx context.Context, db Executor, entity *EntityContext, q *Query) (int64, error) {
+	d := entity.Dialect()
+	statement := fmt.Sprintf("DELETE FROM %s", d.Quote(entity.schema.Table))
+
+	where, values, err := q.condition(d, entity.schema, 1)
+	if err != nil {
+		return 0, err
+	}
+
+	if where != "" {
+		statement += " WHERE " + where
+	}
+
+	return execSQL(ctx, db, d, statement, values...)
+}
+
+func (m *Metadata) queryEntity(model interface{}, q *Query) (*EntityContext, error) {
+	if q == nil {
+		return nil, fmt.Errorf("Query is required")
+	}
+
+	if q.paginated() {
+		return nil, fmt.Errorf("ORDER BY, LIMIT and OFFSET are supported only by SELECT")
+	}
+
+	return m.entity(model)
+}
@@ -0,0 +1,236 @@
+package sqlutil_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+
+	"github.com/phogolabs/sqlutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Query", func() {
+	type student struct {
+		ID     string         `sql:"id,varchar(50),pk"`
+		Name   string         `sql:"name,text"`
+		Grade  int            `sql:"grade,integer"`
+		Mentor sql.NullString `sql:"mentor,text"`
+	}
+
+	BeforeEach(func() {
+		Expect(sqlutil.CreateTable(db, &student{})).To(Succeed())
+		_, err := db.Exec(`INSERT INTO student (id,name,grade,mentor) VALUES
+			('1','Jack',5,'Peter'),
+			('2','John',6,NULL),
+			('3','Jane',4,NULL),
+			('4','Peter',6,'Jack')`)
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		_, err := db.Exec("drop table student")
+		Expect(err).To(BeNil())
+	})
+
+	ids := func(q *sqlutil.Query) []string {
+		students := []student{}
+		Expect(sqlutil.SelectWhere(db, &students, q)).To(Succeed())
+
+		result := []string{}
+		for _, s := range students {
+			result = append(result, s.ID)
+		}
+		return result
+	}
+
+	It("selects all rows without a query", func() {
+		Expect(ids(nil)).To(ConsistOf("1", "2", "3", "4"))
+	})
+
+	It("selects the rows that match the conditions", func() {
+		Expect(ids(sqlutil.Where("name", sqlutil.Eq, "John"))).To(Equal([]string{"2"}))
+		Expect(ids(sqlutil.Where("grade", sqlutil.Gte, 6).OrderBy("id"))).To(Equal([]string{"2", "4"}))
+		Expect(ids(sqlutil.Where("name", sqlutil.Like, "J%").And("grade", sqlutil.Lt, 6).OrderBy("id"))).To(Equal([]string{"1", "3"}))
+		Expect(ids(sqlutil.Where("id", sqlutil.In, []string{"1", "3", "9"}).OrderBy("id"))).To(Equal([]string{"1", "3"}))
+		Expect(ids(sqlutil.Where("id", sqlutil.NotIn, "1", "2").OrderBy("id"))).To(Equal([]string{"3", "4"}))
+		Expect(ids(sqlutil.Where("mentor", sqlutil.IsNull).OrderBy("id"))).To(Equal([]string{"2", "3"}))
+		Expect(ids(sqlutil.Where("mentor", sqlutil.IsNotNull).OrderBy("id"))).To(Equal([]string{"1", "4"}))
+	})
+
+	It("groups the conditions", func() {
+		q := sqlutil.Where("grade", sqlutil.Eq, 6).
+			AndWhere(sqlutil.Where("name", sqlutil.Eq, "John").Or("mentor", sqlutil.Eq, "Jack")).
+			OrderBy("id")
+
+		Expect(ids(q)).To(Equal([]string{"2", "4"}))
+		Expect(ids(sqlutil.Where("id", sqlutil.Eq, "1").Or("id", sqlutil.Eq, "3").OrderByDesc("id"))).To(Equal([]string{"3", "1"}))
+	})
+
+	It("paginates the result", func() {
+		Expect(ids((&sqlutil.Query{}).OrderBy("id").Limit(2))).To(Equal([]string{"1", "2"}))
+		Expect(ids((&sqlutil.Query{}).OrderBy("id").Limit(2).Offset(1))).To(Equal([]string{"2", "3"}))
+		Expect(ids((&sqlutil.Query{}).OrderBy("id").Offset(3))).To(Equal([]string{"4"}))
+	})
+
+	It("updates the rows that match the conditions", func() {
+		cnt, err := sqlutil.UpdateWhere(db, &student{}, sqlutil.Where("grade", sqlutil.Eq, 6), sqlutil.Fields{"grade": 7})
+		Expect(err).To(BeNil())
+		Expect(cnt).To(Equal(int64(2)))
+		Expect(ids(sqlutil.Where("grade", sqlutil.Eq, 7).OrderBy("id"))).To(Equal([]string{"2", "4"}))
+	})
+
+	It("deletes the rows that match the conditions", func() {
+		cnt, err := sqlutil.DeleteWhere(db, &student{}, sqlutil.Where("mentor", sqlutil.IsNull))
+		Expect(err).To(BeNil())
+		Expect(cnt).To(Equal(int64(2)))
+		Expect(ids(nil)).To(ConsistOf("1", "4"))
+	})
+
+	Context("when the column is unknown", func() {
+		It("returns an error", func() {
+			students := []student{}
+			err := sqlutil.SelectWhere(db, &students, sqlutil.Where("surname", sqlutil.Eq, "Doe"))
+			Expect(err).To(MatchError(`Unknown column "surname" for table "student"`))
+
+			err = sqlutil.SelectWhere(db, &students, (&sqlutil.Query{}).OrderBy("surname"))
+			Expect(err).To(MatchError(`Unknown column "surname" for table "student"`))
+
+			_, err = sqlutil.UpdateWhere(db, &student{}, sqlutil.Where("id", sqlutil.Eq, "1"), sqlutil.Fields{"surname": "Doe"})
+			Expect(err).To(MatchError(`Unknown column "surname" for table "student"`))
+		})
+	})
+
+	Context("when the values do not match the operator", func() {
+		It("returns an error", func() {
+			students := []student{}
+			Expect(sqlutil.SelectWhere(db, &students, sqlutil.Where("id", sqlutil.Eq))).To(MatchError(`Operator "=" expects exactly one value`))
+			Expect(sqlutil.SelectWhere(db, &students, sqlutil.Where("id", sqlutil.In, []string{}))).To(MatchError(`Operator "IN" expects at least one value`))
+			Expect(sqlutil.SelectWhere(db, &students, sqlutil.Where("id", sqlutil.IsNull, 1))).To(MatchError(`Operator "IS NULL" does not accept values`))
+		})
+	})
+
+	Context("when the update or delete query is paginated", func() {
+		It("returns an error", func() {
+			_, err := sqlutil.DeleteWhere(db, &student{}, sqlutil.Where("id", sqlutil.Eq, "1").Limit(1))
+			Expect(err).To(MatchError("ORDER BY, LIMIT and OFFSET are supported only by SELECT"))
+		})
+	})
+
+	Context("when the order column is not a schema column", func() {
+		It("returns an error", func() {
+			students := []student{}
+			err := sqlutil.SelectWhere(db, &students, (&sqlutil.Query{}).OrderBy("name UNION SELECT 'x', sqlite_version()"))
+			Expect(err).To(MatchError(`Unknown column "name UNION SELECT 'x', sqlite_version()" for table "student"`))
+
+			err = sqlutil.SelectWhere(db, &students, (&sqlutil.Query{}).OrderByDesc("name DESC"))
+			Expect(err).To(MatchError(`Unknown column "name DESC" for table "student"`))
+		})
+	})
+
+	Context("when the table supports soft delete", func() {
+		type lesson struct {
+			ID        string       `sql:"id,varchar(50),pk"`
+			Grade     int          `sql:"grade,integer"`
+			DeletedAt sql.NullTime `sql:"deleted_at,timestamp"`
+		}
+
+		BeforeEach(func() {
+			Expect(sqlutil.CreateTable(db, &lesson{})).To(Succeed())
+			_, err := db.Exec(`INSERT INTO lesson (id,grade,deleted_at) VALUES
+				('1',5,NULL),
+				('2',6,NULL),
+				('3',6,'2020-01-01 00:00:00')`)
+			Expect(err).To(BeNil())
+		})
+
+		AfterEach(func() {
+			_, err := db.Exec("drop table lesson")
+			Expect(err).To(BeNil())
+		})
+
+		lessons := func(q *sqlutil.Query) []string {
+			items := []lesson{}
+			Expect(sqlutil.SelectWhere(db, &items, q)).To(Succeed())
+
+			result := []string{}
+			for _, item := range items {
+				result = append(result, item.ID)
+			}
+			return result
+		}
+
+		It("excludes the soft-deleted rows", func() {
+			Expect(lessons(nil)).To(ConsistOf("1", "2"))
+			Expect(lessons(sqlutil.Where("grade", sqlutil.Eq, 6).Or("grade", sqlutil.Eq, 5))).To(ConsistOf("1", "2"))
+			Expect(lessons(sqlutil.Where("grade", sqlutil.Eq, 6).IncludeDeleted())).To(ConsistOf("2", "3"))
+
+			cnt, err := sqlutil.UpdateWhere(db, &lesson{}, sqlutil.Where("grade", sqlutil.Eq, 6), sqlutil.Fields{"grade": 7})
+			Expect(err).To(BeNil())
+			Expect(cnt).To(Equal(int64(1)))
+		})
+
+		It("soft-deletes the rows that match the conditions", func() {
+			cnt, err := sqlutil.DeleteWhere(db, &lesson{}, sqlutil.Where("grade", sqlutil.Eq, 6))
+			Expect(err).To(BeNil())
+			Expect(cnt).To(Equal(int64(1)))
+			Expect(lessons(nil)).To(ConsistOf("1"))
+			Expect(lessons((&sqlutil.Query{}).IncludeDeleted())).To(ConsistOf("1", "2", "3"))
+		})
+
+		It("deletes the rows permanently", func() {
+			cnt, err := sqlutil.HardDeleteWhere(db, &lesson{}, sqlutil.Where("grade", sqlutil.Eq, 6).IncludeDeleted())
+			Expect(err).To(BeNil())
+			Expect(cnt).To(Equal(int64(2)))
+			Expect(lessons((&sqlutil.Query{}).IncludeDeleted())).To(ConsistOf("1"))
+		})
+	})
+
+	Context("when the dialect is changed", func() {
+		var recordDB *sql.DB
+
+		BeforeEach(func() {
+			var err error
+			recordDB, err = sql.Open("sqlutil-recorder", "")
+			Expect(err).To(BeNil())
+		})
+
+		AfterEach(func() {
+			sqlutil.SetDialect(sqlutil.SQLiteDialect)
+			Expect(recordDB.Close()).To(Succeed())
+		})
+
+		dialects := []sqlutil.Dialect{
+			sqlutil.SQLiteDialect,
+			sqlutil.PostgreSQLDialect,
+			sqlutil.MySQLDialect,
+			sqlutil.SQLServerDialect,
+		}
+
+		for _, dialect := range dialects {
+			d := dialect
+
+			It("generates "+d.Name()+" statements that match the golden file", func() {
+				sqlutil.SetDialect(d)
+				recorder.Reset()
+
+				q := func() *sqlutil.Query {
+					return sqlutil.Where("grade", sqlutil.Gt, 1).
+						AndWhere(sqlutil.Where("id", sqlutil.In, "1", "2").Or("mentor", sqlutil.IsNull))
+				}
+
+				students := []student{}
+				Expect(sqlutil.SelectWhere(recordDB, &students, q().OrderByDesc("name").Limit(10).Offset(20))).To(Succeed())
+				Expect(sqlutil.SelectWhere(recordDB, &students, (&sqlutil.Query{}).OrderBy("id").Offset(5))).To(Succeed())
+				Expect(sqlutil.SelectWhere(recordDB, &students, (&sqlutil.Query{}).Limit(10))).To(Succeed())
+				_, err := sqlutil.UpdateWhere(recordDB, &student{}, q(), sqlutil.Fields{"name": "Jack"})
+				Expect(err).To(BeNil())
+				_, err = sqlutil.DeleteWhere(recordDB, &student{}, q())
+				Expect(err).To(BeNil())
+
+				Expect(strings.Join(recorder.statements, "\n;\n") + "\n").To(MatchGolden(filepath.Join("query", d.Name())))
+			})
+		}
+	})
+})
@@ -0,0 +1,106 @@
+package sqlutil
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+func Select(db Executor, dest interface{}, query string, args ...interface{}) error {
+	return metadata.SelectContext(context.Background(), db, dest, query, args...)
+}
+
+// SelectContext executes the query and stores all rows in dest, which must be
+// a pointer to a slice of structs or to a slice of pointers to struct.
+func SelectContext(ctx context.Context, db Executor, dest interface{}, query string, args ...interface{}) error {
+	return metadata.SelectContext(ctx, db, dest, query, args...)
+}
+
+func (m *Metadata) Select(db Executor, dest interface{}, query string, args ...interface{}) error {
+	return m.SelectContext(context.Background(), db, dest, query, args...)
+}
+
+// SelectContext executes the query and stores all rows in dest using the
+// schemas of the registry
+func (m *Metadata) SelectContext(ctx context.Context, db Executor, dest interface{}, query string, args ...interface{}) error {
+	slice, err := sliceOf(dest)
+	if err != nil {
+		return err
+	}
+
+	elemType := slice.Type().Elem()
+	structType := elemType
+	if elemType.Kind() == reflect.Ptr {
+		structType = elemType.Elem()
+	}
+
+	schema, err := m.Schema(structType)
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	mapping := scanMapping(schema, columns)
+	items := reflect.MakeSlice(slice.Type(), 0, 0)
+
+	for rows.Next() {
+		item := reflect.New(structType)
+		entity := &EntityContext{
+			metadata:   m,
+			schema:     schema,
+			modelValue: item.Elem(),
+		}
+
+		if err := rows.Scan(entity.scanValues(mapping)...); err != nil {
+			return err
+		}
+
+		if err := entity.afterScan(ctx, db); err != nil {
+			return err
+		}
+
+		if elemType.Kind() != reflect.Ptr {
+			item = item.Elem()
+		}
+
+		items = reflect.Append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	slice.Set(items)
+	return nil
+}
+
+func sliceOf(dest interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(dest)
+
+	if !v.IsValid() || v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return reflect.Value{}, fmt.Errorf("Must be pointer to slice; got %T", dest)
+	}
+
+	t := v.Type()
+
+	elemType := t.Elem().Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+
+	if elemType.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("Must be pointer to slice of structs; got %s", t)
+	}
+
+	return v.Elem(), nil
+}
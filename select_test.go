@@ -0,0 +1,69 @@
+package sqlutil_test
+
+import (
+	"github.com/phogolabs/sqlutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Select", func() {
+	type student struct {
+		ID   string `sql:"id,varchar(50),pk"`
+		Name string `sql:"name,text"`
+	}
+
+	BeforeEach(func() {
+		Expect(sqlutil.CreateTable(db, &student{})).To(Succeed())
+		_, err := db.Exec("INSERT INTO student (id,name) VALUES ('1','Jack'), ('2','John')")
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		_, err := db.Exec("drop table student")
+		Expect(err).To(BeNil())
+	})
+
+	It("selects rows into a slice of values", func() {
+		students := []student{}
+		Expect(sqlutil.Select(db, &students, "SELECT * FROM student ORDER BY id")).To(Succeed())
+		Expect(students).To(Equal([]student{
+			{ID: "1", Name: "Jack"},
+			{ID: "2", Name: "John"},
+		}))
+	})
+
+	It("selects rows into a slice of pointers", func() {
+		students := []*student{}
+		Expect(sqlutil.Select(db, &students, "SELECT name, id FROM student WHERE id = ?", "2")).To(Succeed())
+		Expect(students).To(HaveLen(1))
+		Expect(students[0].ID).To(Equal("2"))
+		Expect(students[0].Name).To(Equal("John"))
+	})
+
+	It("replaces the content of the slice", func() {
+		students := []student{{ID: "3"}}
+		Expect(sqlutil.Select(db, &students, "SELECT * FROM student WHERE id = ?", "unknown")).To(Succeed())
+		Expect(students).To(BeEmpty())
+	})
+
+	Context("when the query fails", func() {
+		It("returns an error", func() {
+			students := []student{}
+			Expect(sqlutil.Select(db, &students, "SELECT * FROM unknown")).To(MatchError("no such table: unknown"))
+		})
+	})
+
+	Context("when the destination is not a pointer to slice", func() {
+		It("returns an error", func() {
+			Expect(sqlutil.Select(db, []student{}, "SELECT * FROM student")).To(MatchError(ContainSubstring("Must be pointer to slice")))
+			Expect(sqlutil.Select(db, &[]string{}, "SELECT * FROM student")).To(MatchError(ContainSubstring("Must be pointer to slice of structs")))
+		})
+
+		It("returns an error for nil", func() {
+			var students *[]student
+			Expect(sqlutil.Select(db, nil, "SELECT * FROM student")).To(MatchError("Must be pointer to slice; got <nil>"))
+			Expect(sqlutil.SelectWhere(db, students, nil)).To(MatchError("Must be pointer to slice; got *[]sqlutil_test.student"))
+		})
+	})
+})
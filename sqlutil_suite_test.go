@@ -2,8 +2,11 @@ package sqlutil_test
 
 import (
 	"database/sql"
+	"database/sql/driver"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	_ "github.com/mattn/go-sqlite3"
 	. "github.com/onsi/ginkgo"
@@ -12,6 +15,8 @@ import (
 	"testing"
 )
 
+var updateGolden = os.Getenv("UPDATE_GOLDEN") != ""
+
 var (
 	db     *sql.DB
 	dbfile string
@@ -36,3 +41,109 @@ var _ = AfterSuite(func() {
 	Expect(db.Close()).To(Succeed())
 	Expect(os.Remove(dbfile)).To(Succeed())
 })
+
+func MatchGolden(name string) OmegaMatcher {
+	path := filepath.Join("testdata", name+".golden")
+
+	if updateGolden {
+		return WithTransform(func(actual string) string {
+			Expect(ioutil.WriteFile(path, []byte(actual), 0644)).To(Succeed())
+			return actual
+		}, Not(BeEmpty()))
+	}
+
+	golden, err := ioutil.ReadFile(path)
+	Expect(err).To(BeNil())
+	return Equal(string(golden))
+}
+
+var recorder = &recordDriver{}
+
+func init() {
+	sql.Register("sqlutil-recorder", recorder)
+}
+
+type recordDriver struct {
+	statements []string
+}
+
+func (d *recordDriver) Open(name string) (driver.Conn, error) {
+	return &recordConn{driver: d}, nil
+}
+
+func (d *recordDriver) Reset() {
+	d.statements = []string{}
+}
+
+type recordConn struct {
+	driver *recordDriver
+}
+
+func (c *recordConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordStmt{conn: c, query: query}, nil
+}
+
+func (c *recordConn) Close() error {
+	return nil
+}
+
+func (c *recordConn) Begin() (driver.Tx, error) {
+	return &recordTx{}, nil
+}
+
+type recordTx struct{}
+
+func (tx *recordTx) Commit() error {
+	return nil
+}
+
+func (tx *recordTx) Rollback() error {
+	return nil
+}
+
+type recordStmt struct {
+	conn  *recordConn
+	query string
+}
+
+func (s *recordStmt) Close() error {
+	return nil
+}
+
+func (s *recordStmt) NumInput() int {
+	return -1
+}
+
+func (s *recordStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.conn.driver.statements = append(s.conn.driver.statements, s.query)
+	return recordResult{}, nil
+}
+
+type recordResult struct{}
+
+func (r recordResult) LastInsertId() (int64, error) {
+	return 1, nil
+}
+
+func (r recordResult) RowsAffected() (int64, error) {
+	return 1, nil
+}
+
+func (s *recordStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.conn.driver.statements = append(s.conn.driver.statements, s.query)
+	return &recordRows{}, nil
+}
+
+type recordRows struct{}
+
+func (r *recordRows) Columns() []string {
+	return []string{}
+}
+
+func (r *recordRows) Close() error {
+	return nil
+}
+
+func (r *recordRows) Next(dest []driver.Value) error {
+	return io.EOF
+}
@@ -1,57 +1,119 @@
 package sqlutil
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
 	"strings"
 )
 
 const Separator = ",\n"
 
-func CreateTable(db *sql.DB, model interface{}) error {
-	t, err := typeOf(model)
-	if err != nil {
-		return err
-	}
+func CreateTable(db Executor, model interface{}) error {
+	return CreateTableContext(context.Background(), db, model)
+}
 
-	schema, err := metadata.Schema(t)
+func CreateTableContext(ctx context.Context, db Executor, model interface{}) error {
+	entity, err := metadata.entity(model)
 	if err != nil {
 		return err
 	}
 
+	return entity.CreateTableContext(ctx, db)
+}
+
+func (t *EntityContext) CreateTable(db Executor) error {
+	return t.CreateTableContext(context.Background(), db)
+}
+
+func (t *EntityContext) CreateTableContext(ctx context.Context, db Executor) error {
+	d := t.Dialect()
+	schema := t.schema
 	definitions := []string{}
 	tablePK := []string{}
+	var inlinePK *Column
 
 	for _, column := range schema.Columns {
-		definition := strings.TrimRight(fmt.Sprintf(" %s %s %s", column.Name, column.DataType, column.Constraint.String()), " ")
+		dataType := d.DataType(column.DataType)
+		if dataType == "" {
+			dataType = d.ColumnType(column.Type)
+		}
+
+		if dataType == "" {
+			return fmt.Errorf("Cannot infer data type of column %q from %s", column.Name, column.Type)
+		}
+
+		if column.AutoIncrement {
+			var inline bool
+			if dataType, inline = d.AutoIncrement(dataType); inline {
+				inlinePK = column
+			}
+		}
+
+		definition := strings.TrimRight(fmt.Sprintf(" %s %s %s", d.Quote(column.Name), dataType, column.Constraint.String()), " ")
 		definitions = append(definitions, definition)
 
 		if column.PrimaryKey {
-			tablePK = append(tablePK, column.Name)
+			tablePK = append(tablePK, d.Quote(column.Name))
 		}
 	}
 
-	definitions = append(definitions, fmt.Sprintf(" CONSTRAINT %s_pk PRIMARY KEY(%s)", schema.Table, strings.Join(tablePK, Separator)))
+	// the data type of an inline auto-increment column declares the primary
+	// key, which therefore cannot have other columns
+	if inlinePK != nil && (!inlinePK.PrimaryKey || len(tablePK) != 1) {
+		return fmt.Errorf("Auto-increment column %q must be the only primary key of table %q", inlinePK.Name, schema.Table)
+	}
+
+	if len(tablePK) > 0 && inlinePK == nil {
+		definitions = append(definitions, fmt.Sprintf(" CONSTRAINT %s PRIMARY KEY(%s)", d.Quote(unqualified(schema.Table)+"_pk"), strings.Join(tablePK, ",")))
+	}
 
 	for _, fk := range schema.ForeignKeys {
 		definitions = append(definitions, fmt.Sprintf(" FOREIGN KEY (%s) REFERENCES %s (%s)",
-			strings.Join(fk.Columns, Separator),
-			fk.ReferenceTable,
-			strings.Join(fk.ReferenceTableColumns, Separator)))
+			strings.Join(quoteAll(d, fk.Columns), ","),
+			d.Quote(fk.ReferenceTable),
+			strings.Join(quoteAll(d, fk.ReferenceTableColumns), ",")))
 	}
 
-	statement := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n%s\n)", schema.Table, strings.Join(definitions, Separator))
+	statement := fmt.Sprintf("CREATE TABLE %s%s (\n%s\n)", ifNotExists(d), d.Quote(schema.Table), strings.Join(definitions, Separator))
 
-	if _, err = db.Exec(statement); err != nil {
+	if _, err := db.ExecContext(ctx, statement); err != nil {
 		return err
 	}
 
 	for _, index := range schema.Indexes {
-		statement := fmt.Sprintf("CREATE INDEX %s ON %s (%s)", index.Name, schema.Table, strings.Join(index.Columns, ","))
-		if _, err := db.Exec(statement); err != nil {
+		statement := d.CreateIndex(index, schema.Table)
+		if _, err := db.ExecContext(ctx, statement); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+func createIndex(d Dialect, index *Index, table string) string {
+	unique := ""
+	if index.Unique {
+		unique = "UNIQUE "
+	}
+
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)", unique, d.Quote(index.Name), d.Quote(table), strings.Join(quoteAll(d, index.Columns), ","))
+}
+
+func ifNotExists(d Dialect) string {
+	if d.IfNotExists() {
+		return "IF NOT EXISTS "
+	}
+	return ""
+}
+
+func quoteAll(d Dialect, identifiers []string) []string {
+	quoted := make([]string, len(identifiers))
+	for index, identifier := range identifiers {
+		quoted[index] = d.Quote(identifier)
+	}
+	return quoted
+}
+
+func unqualified(table string) string {
+	return table[strings.LastIndex(table, ".")+1:]
+}
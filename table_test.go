@@ -63,6 +63,112 @@ var _ = Describe("Table", func() {
 		Expect(isPK).To(Equal(0))
 	})
 
+	It("creates a table with composite primary key", func() {
+		type membership struct {
+			GroupID string `sql:"group_id,varchar(50),pk"`
+			UserID  string `sql:"user_id,varchar(50),pk"`
+			Role    string `sql:"role,text"`
+		}
+
+		Expect(sqlutil.CreateTable(db, &membership{})).To(Succeed())
+		defer db.Exec("drop table membership")
+
+		rows, err := db.Query("pragma table_info(membership)")
+		Expect(err).To(BeNil())
+		defer func() {
+			Expect(rows.Close()).To(Succeed())
+		}()
+
+		keys := map[string]int{}
+
+		for rows.Next() {
+			var (
+				no           int
+				name         string
+				dataType     string
+				notNull      int
+				defaultValue interface{}
+				isPK         int
+			)
+
+			Expect(rows.Scan(&no, &name, &dataType, &notNull, &defaultValue, &isPK)).To(Succeed())
+			keys[name] = isPK
+		}
+
+		Expect(keys).To(Equal(map[string]int{"group_id": 1, "user_id": 2, "role": 0}))
+	})
+
+	It("infers the column data types", func() {
+		type inferred struct {
+			ID      int64     `sql:"id,,pk"`
+			Name    string    `sql:"name"`
+			Active  bool      `sql:"active"`
+			Created time.Time `sql:"created"`
+			Amount  float64   `sql:"amount,numeric"`
+		}
+
+		Expect(sqlutil.CreateTable(db, &inferred{})).To(Succeed())
+		defer db.Exec("drop table inferred")
+
+		rows, err := db.Query("pragma table_info(inferred)")
+		Expect(err).To(BeNil())
+		defer func() {
+			Expect(rows.Close()).To(Succeed())
+		}()
+
+		types := map[string]string{}
+
+		for rows.Next() {
+			var (
+				no           int
+				name         string
+				dataType     string
+				notNull      int
+				defaultValue interface{}
+				isPK         int
+			)
+
+			Expect(rows.Scan(&no, &name, &dataType, &notNull, &defaultValue, &isPK)).To(Succeed())
+			types[name] = dataType
+		}
+
+		Expect(types).To(Equal(map[string]string{
+			"id":      "integer",
+			"name":    "text",
+			"active":  "boolean",
+			"created": "timestamp",
+			"amount":  "numeric",
+		}))
+	})
+
+	Context("when the data type cannot be inferred", func() {
+		It("returns an error", func() {
+			type z struct {
+				ID   string            `sql:"id,varchar(50),pk"`
+				Tags map[string]string `sql:"tags"`
+			}
+
+			Expect(sqlutil.CreateTable(db, &z{})).To(MatchError(`Cannot infer data type of column "tags" from map[string]string`))
+		})
+	})
+
+	Context("when an auto-increment column is not the only primary key", func() {
+		It("returns an error for sqlite", func() {
+			type w struct {
+				ID  string `sql:"id,varchar(50),pk"`
+				Seq int64  `sql:"seq,integer,auto"`
+			}
+
+			type v struct {
+				ID  string `sql:"id,varchar(50),pk"`
+				Seq int64  `sql:"seq,integer,pk,auto"`
+			}
+
+			Expect(sqlutil.CreateTable(db, &w{})).To(MatchError(`Auto-increment column "seq" must be the only primary key of table "w"`))
+			Expect(sqlutil.CreateTable(db, &v{})).To(MatchError(`Auto-increment column "seq" must be the only primary key of table "v"`))
+		})
+	})
+
 	Context("when the provided type is not a pointer", func() {
 		It("create table operation returns an error", func() {
 			type y struct {
@@ -0,0 +1,84 @@
+package sqlutil
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// TxBeginner starts transactions. It is satisfied by *sql.DB and *sql.Conn.
+type TxBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// Tx is a transaction passed to the WithTx callback. Passing it to a nested
+// WithTx call creates a savepoint instead of a new transaction.
+type Tx struct {
+	*sql.Tx
+	dialect Dialect
+	depth   int
+}
+
+// WithTx runs fn in a transaction that is committed when fn returns nil and
+// rolled back when fn returns an error or panics. When db is already a
+// transaction fn runs inside a SAVEPOINT that is released or rolled back.
+func WithTx(ctx context.Context, db Executor, fn func(tx *Tx) error) error {
+	return metadata.WithTx(ctx, db, fn)
+}
+
+// WithTx runs fn in a transaction whose savepoints use the dialect of the
+// registry
+func (m *Metadata) WithTx(ctx context.Context, db Executor, fn func(tx *Tx) error) error {
+	switch executor := db.(type) {
+	case *Tx:
+		return withSavepoint(ctx, &Tx{Tx: executor.Tx, dialect: executor.dialect, depth: executor.depth + 1}, fn)
+	case *sql.Tx:
+		return withSavepoint(ctx, &Tx{Tx: executor, dialect: m.Dialect(), depth: 1}, fn)
+	case TxBeginner:
+		tx, err := executor.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		return runTx(&Tx{Tx: tx, dialect: m.Dialect()}, tx.Commit, tx.Rollback, fn)
+	default:
+		return fmt.Errorf("Executor %T does not support transactions", db)
+	}
+}
+
+func withSavepoint(ctx context.Context, tx *Tx, fn func(tx *Tx) error) error {
+	name := fmt.Sprintf("sqlutil_savepoint_%d", tx.depth)
+
+	savepoint := func(statement string) func() error {
+		return func() error {
+			if statement == "" {
+				return nil
+			}
+
+			_, err := tx.ExecContext(ctx, statement)
+			return err
+		}
+	}
+
+	if err := savepoint(tx.dialect.Savepoint(name))(); err != nil {
+		return err
+	}
+
+	return runTx(tx, savepoint(tx.dialect.ReleaseSavepoint(name)), savepoint(tx.dialect.RollbackToSavepoint(name)), fn)
+}
+
+func runTx(tx *Tx, commit, rollback func() error, fn func(tx *Tx) error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			rollback()
+			panic(p)
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		rollback()
+		return err
+	}
+
+	return commit()
+}
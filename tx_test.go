@@ -0,0 +1,156 @@
+package sqlutil_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/phogolabs/sqlutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tx", func() {
+	type student struct {
+		ID   string `sql:"id,varchar(50),pk"`
+		Name string `sql:"name,text"`
+	}
+
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		Expect(sqlutil.CreateTable(db, &student{})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		_, err := db.Exec("drop table student")
+		Expect(err).To(BeNil())
+	})
+
+	ids := func() []string {
+		rows, err := db.Query("SELECT id FROM student ORDER BY id")
+		Expect(err).To(BeNil())
+		defer rows.Close()
+
+		result := []string{}
+		for rows.Next() {
+			id := ""
+			Expect(rows.Scan(&id)).To(Succeed())
+			result = append(result, id)
+		}
+
+		return result
+	}
+
+	It("commits the transaction", func() {
+		err := sqlutil.WithTx(ctx, db, func(tx *sqlutil.Tx) error {
+			_, err := sqlutil.Insert(tx, &student{ID: "1", Name: "Jack"})
+			return err
+		})
+
+		Expect(err).To(BeNil())
+		Expect(ids()).To(Equal([]string{"1"}))
+	})
+
+	It("rolls back the transaction when an error is returned", func() {
+		err := sqlutil.WithTx(ctx, db, func(tx *sqlutil.Tx) error {
+			_, err := sqlutil.Insert(tx, &student{ID: "1", Name: "Jack"})
+			Expect(err).To(BeNil())
+			return fmt.Errorf("oh no")
+		})
+
+		Expect(err).To(MatchError("oh no"))
+		Expect(ids()).To(BeEmpty())
+	})
+
+	It("rolls back the transaction when the function panics", func() {
+		Expect(func() {
+			sqlutil.WithTx(ctx, db, func(tx *sqlutil.Tx) error {
+				_, err := sqlutil.Insert(tx, &student{ID: "1", Name: "Jack"})
+				Expect(err).To(BeNil())
+				panic("oh no")
+			})
+		}).To(PanicWith("oh no"))
+
+		Expect(ids()).To(BeEmpty())
+	})
+
+	Context("when the transactions are nested", func() {
+		It("rolls back only the failed savepoint", func() {
+			err := sqlutil.WithTx(ctx, db, func(tx *sqlutil.Tx) error {
+				_, err := sqlutil.Insert(tx, &student{ID: "1", Name: "Jack"})
+				Expect(err).To(BeNil())
+
+				err = sqlutil.WithTx(ctx, tx, func(tx *sqlutil.Tx) error {
+					_, err := sqlutil.Insert(tx, &student{ID: "2", Name: "John"})
+					Expect(err).To(BeNil())
+					return fmt.Errorf("oh no")
+				})
+				Expect(err).To(MatchError("oh no"))
+
+				return sqlutil.WithTx(ctx, tx, func(tx *sqlutil.Tx) error {
+					_, err := sqlutil.Insert(tx, &student{ID: "3", Name: "Peter"})
+					return err
+				})
+			})
+
+			Expect(err).To(BeNil())
+			Expect(ids()).To(Equal([]string{"1", "3"}))
+		})
+
+		It("rolls back the released savepoints with the outer transaction", func() {
+			err := sqlutil.WithTx(ctx, db, func(tx *sqlutil.Tx) error {
+				err := sqlutil.WithTx(ctx, tx, func(tx *sqlutil.Tx) error {
+					_, err := sqlutil.Insert(tx, &student{ID: "1", Name: "Jack"})
+					return err
+				})
+				Expect(err).To(BeNil())
+				return fmt.Errorf("oh no")
+			})
+
+			Expect(err).To(MatchError("oh no"))
+			Expect(ids()).To(BeEmpty())
+		})
+
+		It("uses a savepoint inside a sql transaction", func() {
+			tx, err := db.Begin()
+			Expect(err).To(BeNil())
+
+			err = sqlutil.WithTx(ctx, tx, func(tx *sqlutil.Tx) error {
+				_, err := sqlutil.Insert(tx, &student{ID: "1", Name: "Jack"})
+				Expect(err).To(BeNil())
+				return fmt.Errorf("oh no")
+			})
+			Expect(err).To(MatchError("oh no"))
+
+			_, err = sqlutil.Insert(tx, &student{ID: "2", Name: "John"})
+			Expect(err).To(BeNil())
+
+			Expect(tx.Commit()).To(Succeed())
+			Expect(ids()).To(Equal([]string{"2"}))
+		})
+
+		It("uses the savepoint statements of the dialect", func() {
+			recordDB, err := sql.Open("sqlutil-recorder", "")
+			Expect(err).To(BeNil())
+			defer recordDB.Close()
+			recorder.Reset()
+
+			metadata := sqlutil.NewMetadata(sqlutil.MetadataOptions{Dialect: sqlutil.SQLServerDialect})
+
+			err = metadata.WithTx(ctx, recordDB, func(tx *sqlutil.Tx) error {
+				Expect(metadata.WithTx(ctx, tx, func(tx *sqlutil.Tx) error { return nil })).To(Succeed())
+				return metadata.WithTx(ctx, tx, func(tx *sqlutil.Tx) error { return fmt.Errorf("oh no") })
+			})
+			Expect(err).To(MatchError("oh no"))
+
+			Expect(recorder.statements).To(Equal([]string{
+				"SAVE TRANSACTION sqlutil_savepoint_1",
+				"SAVE TRANSACTION sqlutil_savepoint_1",
+				"ROLLBACK TRANSACTION sqlutil_savepoint_1",
+			}))
+		})
+	})
+})
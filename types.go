@@ -0,0 +1,91 @@
+package sqlutil
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// logicalType is a dialect independent category of Go types that is mapped
+// to a column data type by every dialect
+type logicalType int
+
+const (
+	unknownType logicalType = iota
+	boolType
+	smallIntType
+	intType
+	bigIntType
+	floatType
+	doubleType
+	decimalType
+	stringType
+	timeType
+	bytesType
+)
+
+var (
+	timeReflectType  = reflect.TypeOf(time.Time{})
+	bytesReflectType = reflect.TypeOf([]byte{})
+)
+
+func logicalTypeOf(t reflect.Type) logicalType {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if value, ok := nullValueType(t); ok {
+		return logicalTypeOf(value)
+	}
+
+	if strings.Contains(strings.ToLower(t.Name()), "decimal") {
+		return decimalType
+	}
+
+	switch {
+	case t.Kind() == reflect.Struct && t.ConvertibleTo(timeReflectType):
+		return timeType
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return bytesType
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return boolType
+	case reflect.Int8, reflect.Int16, reflect.Uint8:
+		return smallIntType
+	case reflect.Int32, reflect.Uint16:
+		return intType
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return bigIntType
+	case reflect.Float32:
+		return floatType
+	case reflect.Float64:
+		return doubleType
+	case reflect.String:
+		return stringType
+	default:
+		return unknownType
+	}
+}
+
+// nullValueType returns the type of the value of sql.NullString and similar
+// types that have a Valid field and a single value field
+func nullValueType(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() != reflect.Struct || t.NumField() != 2 {
+		return nil, false
+	}
+
+	valid, ok := t.FieldByName("Valid")
+	if !ok || valid.Type.Kind() != reflect.Bool {
+		return nil, false
+	}
+
+	for index := 0; index < t.NumField(); index++ {
+		if field := t.Field(index); field.Name != "Valid" {
+			return field.Type, true
+		}
+	}
+
+	return nil, false
+}
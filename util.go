@@ -1,23 +1,49 @@
 package sqlutil
 
-import "database/sql"
+import (
+	"context"
+)
 
-func QueryRow(db *sql.DB, model interface{}) error {
+func QueryRow(db Executor, model interface{}) error {
 	return NewEntityContext(model).QueryRow(db)
 }
 
-func Insert(db *sql.DB, model interface{}) (int64, error) {
+func QueryRowContext(ctx context.Context, db Executor, model interface{}) error {
+	return NewEntityContext(model).QueryRowContext(ctx, db)
+}
+
+func Insert(db Executor, model interface{}) (int64, error) {
 	return NewEntityContext(model).Insert(db)
 }
 
-func Update(db *sql.DB, model interface{}, fields ...Fields) (int64, error) {
+func InsertContext(ctx context.Context, db Executor, model interface{}) (int64, error) {
+	return NewEntityContext(model).InsertContext(ctx, db)
+}
+
+func Update(db Executor, model interface{}, fields ...Fields) (int64, error) {
 	return NewEntityContext(model).Update(db, fields...)
 }
 
-func Delete(db *sql.DB, model interface{}) (int64, error) {
+func UpdateContext(ctx context.Context, db Executor, model interface{}, fields ...Fields) (int64, error) {
+	return NewEntityContext(model).UpdateContext(ctx, db, fields...)
+}
+
+func Upsert(db Executor, model interface{}, fields ...Fields) (int64, error) {
+	return NewEntityContext(model).Upsert(db, fields...)
+}
+
+func UpsertContext(ctx context.Context, db Executor, model interface{}, fields ...Fields) (int64, error) {
+	return NewEntityContext(model).UpsertContext(ctx, db, fields...)
+}
+
+func Delete(db Executor, model interface{}) (int64, error) {
 	return NewEntityContext(model).Delete(db)
 }
 
+func DeleteContext(ctx context.Context, db Executor, model interface{}) (int64, error) {
+	return NewEntityContext(model).DeleteContext(ctx, db)
+}
+
 func mergeFields(fields []Fields) (Fields, bool) {
 	allFields := Fields{}
 	merged := false
@@ -32,10 +58,33 @@ func mergeFields(fields []Fields) (Fields, bool) {
 	return allFields, merged
 }
 
-func execSQL(db *sql.DB, statement string, values ...interface{}) (int64, error) {
-	result, err := db.Exec(statement, values...)
+func contains(items []string, item string) bool {
+	for _, current := range items {
+		if current == item {
+			return true
+		}
+	}
+	return false
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for index := range a {
+		if a[index] != b[index] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func execSQL(ctx context.Context, db Executor, d Dialect, statement string, values ...interface{}) (int64, error) {
+	result, err := db.ExecContext(ctx, statement, values...)
 	if err != nil {
-		return 0, err
+		return 0, d.TranslateError(err)
 	}
 
 	cnt, err := result.RowsAffected()
@@ -45,3 +94,12 @@ func execSQL(db *sql.DB, statement string, values ...interface{}) (int64, error)
 
 	return cnt, nil
 }
+
+func execAffectingSQL(ctx context.Context, db Executor, d Dialect, statement string, values ...interface{}) (int64, error) {
+	cnt, err := execSQL(ctx, db, d, statement, values...)
+	if err == nil && cnt == 0 {
+		err = ErrNoRowsAffected
+	}
+
+	return cnt, err
+}